@@ -2,41 +2,167 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
 	"time"
+	// Часовые пояса нужны и в образе без системной базы tzdata
+	_ "time/tzdata"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/api/grpc"
 	"github.com/sashaem1/ExchangeRate/internal/api/http"
+	"github.com/sashaem1/ExchangeRate/internal/broker"
+	"github.com/sashaem1/ExchangeRate/internal/calendar"
+	"github.com/sashaem1/ExchangeRate/internal/clock"
+	filearchive "github.com/sashaem1/ExchangeRate/internal/fileArchive"
 	freecurrencyapi "github.com/sashaem1/ExchangeRate/internal/freeCurrencyAPI"
+	"github.com/sashaem1/ExchangeRate/internal/lifecycle"
+	"github.com/sashaem1/ExchangeRate/internal/logger"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
 	"github.com/sashaem1/ExchangeRate/internal/postgresql"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+	"github.com/sashaem1/ExchangeRate/internal/webhook"
 
 	_ "github.com/lib/pq"
 )
 
+// Дедлайны остановки компонентов. Сумма должна укладываться в
+// stop_grace_period из docker-compose.yml.
+const (
+	shutdownTimeout          = 3 * time.Second
+	serverShutdownTimeout    = 10 * time.Second
+	actionLogShutdownTimeout = 12 * time.Second
+)
+
+// rateBrokerBufferSize - сколько обновлений курсов может ждать чтения у
+// одного подписчика, прежде чем его отключат.
+const rateBrokerBufferSize = 64
+
+const webhookTimeout = 10 * time.Second
+
+const (
+	defaultHTTPPort = "8000"
+	defaultGRPCPort = "9000"
+)
+
 func main() {
+	err := logger.Setup(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		fatal("logger setup failed", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), os.Getenv("OTEL_TRACES_EXPORTER"))
+	if err != nil {
+		fatal("tracing setup failed", err)
+	}
+
+	businessClock := initBusinessClock()
 	pgxPool := initDbConnect()
-	exchangeStorage := postgresql.NewExchangeStorage(pgxPool)
+	err = postgresql.Migrate(context.Background(), pgxPool)
+	if err != nil {
+		fatal("database migration failed", err)
+	}
+	// "ExchangeRate migrate" только обновляет схему бд и завершается
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		pgxPool.Close()
+		return
+	}
+	exchangeStorage := postgresql.NewExchangeStorage(pgxPool, businessClock)
 	externalAPIKey := os.Getenv("FREECURRENCY_API_KEY")
 	ExchangeExternalAPI := freecurrencyapi.NewExchangeExternalAPI(externalAPIKey)
-	exchangeRepo := internal.NewExchangeRepository(exchangeStorage, ExchangeExternalAPI)
+	rateBroker := broker.New[internal.Exchange](rateBrokerBufferSize)
+	metrics.RegisterGaugeFunc("rate_stream_subscribers", "Количество активных подписок на обновления курсов.",
+		func() float64 { return float64(rateBroker.Subscribers()) })
+	metrics.RegisterCounterFunc("rate_stream_dropped_total", "Количество подписок, закрытых из-за медленного чтения.",
+		func() float64 { return float64(rateBroker.Dropped()) })
+	businessCalendar, err := calendar.Load(os.Getenv("CALENDAR_FILE"))
+	if err != nil {
+		fatal("business calendar is invalid", err)
+	}
+	currentYear := businessClock.Date(businessClock.Now()).Year()
+	if markets := businessCalendar.MissingYear(currentYear); len(markets) > 0 {
+		slog.Warn("business calendar has no holidays for the current year, only weekends and annual holidays apply",
+			"year", currentYear, "markets", markets)
+	}
+	datePolicy := internal.NewDatePolicy(businessClock, map[string]time.Time{
+		ExchangeExternalAPI.Name(): freecurrencyapi.EarliestDate,
+	})
+	exchangeRepo := internal.NewExchangeRepository(exchangeStorage, postgresql.NewCandleStorage(pgxPool), ExchangeExternalAPI, rateBroker, businessCalendar, businessClock, datePolicy)
+	err = exchangeRepo.SetObserveSchedule(os.Getenv("RATE_OBSERVE_CRON"))
+	if err != nil {
+		fatal("rate observe schedule is invalid", err)
+	}
 
 	apiKeyStorage := postgresql.NewAPIKeyStorage(pgxPool)
 	apiKeyRepo := internal.NewAPIKeyRepository(apiKeyStorage)
 
 	actionLogStorage := postgresql.NewActionLogStorage(pgxPool)
 	actionLogRepository := internal.NewActionLogRepository(actionLogStorage)
+	metrics.RegisterCounterFunc("action_log_dropped_total", "Количество отброшенных записей лога действий.",
+		func() float64 { return float64(actionLogRepository.Dropped()) })
+	metrics.RegisterGaugeFunc("action_log_queue_length", "Количество записей лога действий в очереди на запись.",
+		func() float64 { return float64(actionLogRepository.QueueLength()) })
+
+	actionLogRetention := initActionLogRetention(actionLogStorage, businessClock)
+	err = actionLogRetention.InitActionLogRetention(context.Background())
+	if err != nil {
+		slog.Error("action log retention failed", "error", err)
+	}
+
+	subscriptionStorage := postgresql.NewSubscriptionStorage(pgxPool)
+	subscriptionRepo := internal.NewSubscriptionRepository(subscriptionStorage, exchangeStorage, webhook.NewSender(webhookTimeout))
+	subscriptionRepo.Start(rateBroker)
 
-	httpServer := http.NewServer(exchangeRepo, apiKeyRepo, actionLogRepository)
+	httpServer := http.NewServer(exchangeRepo, apiKeyRepo, actionLogRepository, rateBroker, subscriptionRepo, businessClock)
+	httpServer.AddReadinessCheck(http.PingCheck("database", pgxPool))
+	httpServer.AddReadinessCheck(http.CircuitCheck("provider.freecurrencyapi", ExchangeExternalAPI))
 	httpHandler := http.NewHandler(httpServer)
 
-	err := httpServer.Start("8000", httpHandler.InitRouters())
-	if err != nil {
-		log.Fatalf("Ошибка старта сервера: %s", err)
+	grpcServer := grpc.NewServer(exchangeRepo, apiKeyRepo, actionLogRepository)
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = defaultGRPCPort
 	}
 
+	manager := lifecycle.NewManager(shutdownTimeout)
+	// Брокер закрывается первым, иначе открытые потоки SSE не дадут http
+	// серверу завершиться.
+	manager.OnShutdown("rate broker", func(ctx context.Context) error {
+		rateBroker.Close()
+		return nil
+	})
+	manager.OnShutdownWithin("http server", serverShutdownTimeout, httpServer.Shutdown)
+	manager.OnShutdownWithin("grpc server", serverShutdownTimeout, grpcServer.Shutdown)
+	manager.OnShutdown("webhook subscriptions", subscriptionRepo.Stop)
+	manager.OnShutdown("exchange scheduler", exchangeRepo.Stop)
+	manager.OnShutdown("action log retention scheduler", actionLogRetention.Stop)
+	// Запись лога успевает сбросить последнюю пачку с повторной попыткой до
+	// закрытия пула соединений.
+	manager.OnShutdownWithin("action log writer", actionLogShutdownTimeout, actionLogRepository.Close)
+	manager.OnShutdown("database pool", func(ctx context.Context) error {
+		pgxPool.Close()
+		return nil
+	})
+	manager.OnShutdown("tracing", shutdownTracing)
+
+	err = manager.Run(func() error {
+		serveErr := make(chan error, 2)
+		go func() {
+			serveErr <- httpServer.Start(defaultHTTPPort, httpHandler.InitRouters())
+		}()
+		go func() {
+			serveErr <- grpcServer.Start(grpcPort)
+		}()
+
+		return <-serveErr
+	})
+	if err != nil {
+		fatal("server stopped with error", err)
+	}
 }
 
 func initDbConnect() *pgxpool.Pool {
@@ -50,7 +176,7 @@ func initDbConnect() *pgxpool.Pool {
 	dbPassword := os.Getenv("DB_PASSWORD")
 
 	if dbHost == "" || dbPort == "" || dbUser == "" || dbPassword == "" || dbName == "" {
-		log.Fatalf("Не хватает данных из переменных окружения для подключения к бд")
+		fatal("database connection settings are missing", errors.New("DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_NAME are required"))
 	}
 
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
@@ -58,7 +184,7 @@ func initDbConnect() *pgxpool.Pool {
 
 	pgxPool, err := pgxpool.New(ctx, connStr)
 	if err != nil {
-		log.Fatalf("%s: %s", op, err)
+		fatal("database pool setup failed", fmt.Errorf("%s: %w", op, err))
 	}
 
 	const maxAttempts = 10
@@ -68,11 +194,11 @@ func initDbConnect() *pgxpool.Pool {
 
 		err = pgxPool.Ping(ctx)
 		if err == nil {
-			log.Printf("Успешное подключение к базе данных")
+			slog.Info("database connected", "host", dbHost, "database", dbName)
 			return pgxPool
 		}
 
-		log.Printf("Пинг базы данных %d/%d. Ошибка: %v", attempt, maxAttempts, err)
+		slog.Warn("database ping failed", "attempt", attempt, "max_attempts", maxAttempts, "error", err)
 
 		if attempt < maxAttempts {
 			time.Sleep(retryDelay)
@@ -80,6 +206,79 @@ func initDbConnect() *pgxpool.Pool {
 	}
 
 	pgxPool.Close()
-	log.Fatalf("%s: %s", op, "Не удалось подключиться к бд")
+	fatal("database unreachable", fmt.Errorf("%s: %w", op, err))
 	return nil
 }
+
+func initActionLogRetention(storage internal.ActionLogPartitionStorage, clock internal.Clock) *internal.ActionLogRetention {
+	op := "main.main.initActionLogRetention"
+
+	retentionDays, err := envInt("ACTION_LOG_RETENTION_DAYS")
+	if err != nil {
+		fatal("action log retention config is invalid", fmt.Errorf("%s: %w", op, err))
+	}
+
+	precreateDays, err := envInt("ACTION_LOG_PRECREATE_DAYS")
+	if err != nil {
+		fatal("action log retention config is invalid", fmt.Errorf("%s: %w", op, err))
+	}
+
+	config, err := internal.NewActionLogRetentionConfig(retentionDays, precreateDays,
+		os.Getenv("ACTION_LOG_RETENTION_MODE"), os.Getenv("ACTION_LOG_RETENTION_CRON"))
+	if err != nil {
+		fatal("action log retention config is invalid", fmt.Errorf("%s: %w", op, err))
+	}
+
+	var archiver internal.ActionLogArchiver
+	if config.Mode == internal.ActionLogRetentionArchive {
+		archiveDir := os.Getenv("ACTION_LOG_ARCHIVE_DIR")
+		if archiveDir == "" {
+			archiveDir = "archive"
+		}
+		archiver = filearchive.NewActionLogArchiver(archiveDir)
+	}
+
+	return internal.NewActionLogRetention(storage, archiver, config, clock)
+}
+
+// initBusinessClock создаёт часы сервиса по BUSINESS_TIMEZONE (по умолчанию
+// UTC) и RATE_PUBLICATION_CUTOFF (по умолчанию полночь). Часовой пояс
+// контейнера на даты курсов не влияет.
+func initBusinessClock() *clock.Business {
+	op := "main.main.initBusinessClock"
+
+	location := time.UTC
+	if name := os.Getenv("BUSINESS_TIMEZONE"); name != "" {
+		var err error
+		location, err = time.LoadLocation(name)
+		if err != nil {
+			fatal("business timezone is invalid", fmt.Errorf("%s: %w", op, err))
+		}
+	}
+
+	cutoff, err := clock.ParseCutoff(os.Getenv("RATE_PUBLICATION_CUTOFF"))
+	if err != nil {
+		fatal("rate publication cutoff is invalid", fmt.Errorf("%s: %w", op, err))
+	}
+
+	return clock.NewBusiness(location, cutoff)
+}
+
+func envInt(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	return result, nil
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
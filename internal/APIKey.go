@@ -2,16 +2,49 @@ package internal
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 )
 
+// apiKeyHashPrefix отличает хэш ключа от самого ключа в логе действий.
+const apiKeyHashPrefix string = "sha256:"
+
 type APIKeyID string
 
 type APIKey struct {
 	ID    APIKeyID
 	Key   string
 	Valid bool
+	// Admin разрешает читать лог действий всех ключей.
+	Admin bool
+	// Pricing - профиль наценки ключа, nil если курсы отдаются без спреда.
+	Pricing *PricingProfile
+}
+
+// HashAPIKey возвращает хэш ключа, под которым ключ хранится в логе действий
+// и его архивах. Сам ключ туда не пишется.
+func HashAPIKey(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return apiKeyHashPrefix + hex.EncodeToString(sum[:])
+}
+
+type apiKeyContextKey struct{}
+
+// ContextWithAPIKey сохраняет проверенный ключ в контексте запроса, чтобы
+// обработчики могли применить его профиль наценки.
+func ContextWithAPIKey(ctx context.Context, apiKey APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, apiKey)
+}
+
+func APIKeyFromContext(ctx context.Context) (APIKey, bool) {
+	apiKey, ok := ctx.Value(apiKeyContextKey{}).(APIKey)
+	return apiKey, ok
 }
 
 func NewAPIKey(key string) APIKey {
@@ -33,13 +66,12 @@ func NewAPIKeyRepository(storage APIKeyStorage) *APIKeyRepository {
 	}
 }
 
-func (rr *APIKeyRepository) VerificationAPIKey(key string) (APIKey, error) {
+func (rr *APIKeyRepository) VerificationAPIKey(ctx context.Context, key string) (APIKey, error) {
 	op := "internal.APIKey.VerificationAPIKey"
-	ctx := context.Background()
 
 	verAPIKey, err := rr.storage.Get(ctx, key)
 	if err != nil {
-		return APIKey{}, fmt.Errorf("%s: %s", op, err)
+		return APIKey{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return verAPIKey, nil
@@ -49,10 +81,12 @@ func (rr *APIKeyRepository) InitAPIKeyRepository(ctx context.Context) error {
 	op := "internal.APIKey.initAPIKey"
 	envAPIKeyStr := os.Getenv("DEFAULT_API_KEY")
 	envAPIKey := NewAPIKey(envAPIKeyStr)
+	// Ключ из окружения - ключ оператора сервиса.
+	envAPIKey.Admin = true
 
 	err := rr.initAPIKey(ctx, envAPIKey)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
@@ -63,7 +97,7 @@ func (rr *APIKeyRepository) initAPIKey(ctx context.Context, APIKey APIKey) error
 
 	err := rr.storage.Set(ctx, APIKey)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
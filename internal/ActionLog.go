@@ -3,6 +3,10 @@ package internal
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,46 +15,259 @@ type ActionLogID string
 type ActionLog struct {
 	ID            ActionLogID
 	ActionLogType ActionLogType
-	Timestamp     time.Time
+	// APIKey - хэш ключа из HashAPIKey.
+	APIKey    string
+	Payload   map[string]any
+	Timestamp time.Time
 }
 
-func NewActionLog(typeName string, timestamp time.Time) (ActionLog, error) {
+// ActionLogFilter описывает выборку из лога действий. Types принимает как
+// точные имена ("rate.pair"), так и целые пространства имён ("rate.*").
+type ActionLogFilter struct {
+	Types  []string
+	APIKey string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+const (
+	actionLogQueueSize      int           = 1024
+	actionLogBatchSize      int           = 100
+	actionLogFlushInterval  time.Duration = time.Second
+	actionLogEnqueueTimeout time.Duration = 50 * time.Millisecond
+	actionLogFlushTimeout   time.Duration = 5 * time.Second
+	// actionLogFlushRetryDelay - пауза перед единственной повторной попыткой
+	// записать пачку, после неё пачка отбрасывается.
+	actionLogFlushRetryDelay time.Duration = 500 * time.Millisecond
+
+	actionLogDefaultLimit int = 100
+	actionLogMaxLimit     int = 1000
+)
+
+func NewActionLog(typeName, apiKey string, payload map[string]any, timestamp time.Time) (ActionLog, error) {
 	op := "internal.logDb.NewActionLog"
 	logDbType, err := NewActionLogType(typeName)
 
 	if err != nil {
-		return ActionLog{}, fmt.Errorf("%s: %s", op, err)
+		return ActionLog{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if payload == nil {
+		payload = map[string]any{}
 	}
 
 	logDb := ActionLog{
 		ActionLogType: logDbType,
+		APIKey:        HashAPIKey(apiKey),
+		Payload:       payload,
 		Timestamp:     timestamp,
 	}
 
 	return logDb, nil
 }
 
+func NewActionLogFilter(types []string, apiKey string, from, to time.Time, limit, offset int) (ActionLogFilter, error) {
+	op := "internal.logDb.NewActionLogFilter"
+
+	for _, actionType := range types {
+		namespace, isWildcard := strings.CutSuffix(actionType, ".*")
+		if isWildcard {
+			actionType = namespace + ".any"
+		}
+
+		if err := validateActionLogTypeName(actionType); err != nil {
+			return ActionLogFilter{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		err := ErrInvalidTimeRange.With(map[string]any{"from": from, "to": to})
+		return ActionLogFilter{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if limit <= 0 {
+		limit = actionLogDefaultLimit
+	}
+	if limit > actionLogMaxLimit {
+		limit = actionLogMaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := ActionLogFilter{
+		Types:  types,
+		APIKey: HashAPIKey(apiKey),
+		From:   from,
+		To:     to,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	return filter, nil
+}
+
 type ActionLogStorage interface {
 	Set(ctx context.Context, ActionLog ActionLog) error
+	SetBatch(ctx context.Context, ActionLogs []ActionLog) error
+	List(ctx context.Context, filter ActionLogFilter) ([]ActionLog, error)
 }
 
+// ActionLogRepository пишет логи действий в фоне: записи копятся в очереди
+// и сбрасываются в хранилище пачками по размеру или по таймеру.
 type ActionLogRepository struct {
-	storage ActionLogStorage
+	storage       ActionLogStorage
+	queue         chan ActionLog
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.RWMutex
+	closed  bool
+	done    chan struct{}
+	dropped atomic.Uint64
 }
 
 func NewActionLogRepository(storage ActionLogStorage) *ActionLogRepository {
-	return &ActionLogRepository{
-		storage: storage,
+	rr := &ActionLogRepository{
+		storage:       storage,
+		queue:         make(chan ActionLog, actionLogQueueSize),
+		batchSize:     actionLogBatchSize,
+		flushInterval: actionLogFlushInterval,
+		done:          make(chan struct{}),
 	}
+
+	go rr.run()
+
+	return rr
 }
 
+// InsertLog ставит запись в очередь. Если очередь заполнена, вызывающий ждёт
+// не дольше actionLogEnqueueTimeout, после чего запись отбрасывается и
+// учитывается в счётчике Dropped.
 func (rr *ActionLogRepository) InsertLog(ctx context.Context, ActionLog ActionLog) error {
 	op := "internal.logDb.InsertLog"
 
-	err := rr.storage.Set(ctx, ActionLog)
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+
+	if rr.closed {
+		rr.dropped.Add(1)
+		return fmt.Errorf("%s: Запись логов остановлена", op)
+	}
+
+	select {
+	case rr.queue <- ActionLog:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(actionLogEnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case rr.queue <- ActionLog:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	rr.dropped.Add(1)
+	return fmt.Errorf("%s: Очередь логов переполнена, запись отброшена", op)
+}
+
+func (rr *ActionLogRepository) ListLogs(ctx context.Context, filter ActionLogFilter) ([]ActionLog, error) {
+	op := "internal.logDb.ListLogs"
+
+	logs, err := rr.storage.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return logs, nil
+}
+
+func (rr *ActionLogRepository) Dropped() uint64 {
+	return rr.dropped.Load()
+}
+
+func (rr *ActionLogRepository) QueueLength() int {
+	return len(rr.queue)
+}
+
+// Close прекращает приём новых записей и дожидается сброса очереди в хранилище.
+func (rr *ActionLogRepository) Close(ctx context.Context) error {
+	op := "internal.logDb.Close"
+
+	rr.mu.Lock()
+	if !rr.closed {
+		rr.closed = true
+		close(rr.queue)
+	}
+	rr.mu.Unlock()
+
+	select {
+	case <-rr.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
+
+func (rr *ActionLogRepository) run() {
+	defer close(rr.done)
+
+	ticker := time.NewTicker(rr.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]ActionLog, 0, rr.batchSize)
+
+	for {
+		select {
+		case actionLog, ok := <-rr.queue:
+			if !ok {
+				rr.flush(batch)
+				return
+			}
+
+			batch = append(batch, actionLog)
+			if len(batch) >= rr.batchSize {
+				rr.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			rr.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (rr *ActionLogRepository) flush(batch []ActionLog) {
+	op := "internal.logDb.flush"
+
+	if len(batch) == 0 {
+		return
+	}
+
+	err := rr.setBatch(batch)
+	if err == nil {
+		return
+	}
+
+	slog.Warn("flushing action log batch failed, retrying", "op", op, "batch_size", len(batch), "error", err)
+	time.Sleep(actionLogFlushRetryDelay)
+
+	err = rr.setBatch(batch)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		rr.dropped.Add(uint64(len(batch)))
+		slog.Error("action log batch dropped", "op", op, "batch_size", len(batch), "error", err)
 	}
+}
+
+func (rr *ActionLogRepository) setBatch(batch []ActionLog) error {
+	ctx, cancel := context.WithTimeout(context.Background(), actionLogFlushTimeout)
+	defer cancel()
 
-	return nil
+	return rr.storage.SetBatch(ctx, batch)
 }
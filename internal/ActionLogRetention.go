@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+)
+
+const (
+	ActionLogRetentionDrop    string = "drop"
+	ActionLogRetentionArchive string = "archive"
+)
+
+const (
+	actionLogDefaultRetentionDays int    = 90
+	actionLogDefaultPrecreateDays int    = 7
+	actionLogDefaultRetentionCron string = "30 0 * * *"
+)
+
+// ActionLogPartition - дневная партиция таблицы лога действий. У партиции по
+// умолчанию IsDefault выставлен в true, а границы не заданы.
+type ActionLogPartition struct {
+	Name      string
+	From      time.Time
+	To        time.Time
+	IsDefault bool
+}
+
+type ActionLogRetentionConfig struct {
+	RetentionDays int
+	PrecreateDays int
+	Mode          string
+	Schedule      string
+}
+
+func NewActionLogRetentionConfig(retentionDays, precreateDays int, mode, schedule string) (ActionLogRetentionConfig, error) {
+	op := "internal.ActionLogRetention.NewActionLogRetentionConfig"
+
+	if retentionDays == 0 {
+		retentionDays = actionLogDefaultRetentionDays
+	}
+	if precreateDays == 0 {
+		precreateDays = actionLogDefaultPrecreateDays
+	}
+	if mode == "" {
+		mode = ActionLogRetentionArchive
+	}
+	if schedule == "" {
+		schedule = actionLogDefaultRetentionCron
+	}
+
+	if retentionDays < 1 {
+		return ActionLogRetentionConfig{}, fmt.Errorf("%s: Срок хранения логов должен быть не меньше одного дня", op)
+	}
+
+	if precreateDays < 1 {
+		return ActionLogRetentionConfig{}, fmt.Errorf("%s: Количество заранее создаваемых партиций должно быть положительным", op)
+	}
+
+	if mode != ActionLogRetentionDrop && mode != ActionLogRetentionArchive {
+		return ActionLogRetentionConfig{}, fmt.Errorf("%s: Неизвестный режим хранения логов: %s", op, mode)
+	}
+
+	config := ActionLogRetentionConfig{
+		RetentionDays: retentionDays,
+		PrecreateDays: precreateDays,
+		Mode:          mode,
+		Schedule:      schedule,
+	}
+
+	return config, nil
+}
+
+type ActionLogPartitionStorage interface {
+	CreatePartition(ctx context.Context, day time.Time) error
+	ListPartitions(ctx context.Context) ([]ActionLogPartition, error)
+	ReadPartition(ctx context.Context, partition ActionLogPartition, before time.Time, fn func(ActionLog) error) error
+	DropPartition(ctx context.Context, partition ActionLogPartition) error
+	DeleteFromPartition(ctx context.Context, partition ActionLogPartition, before time.Time) error
+}
+
+type ActionLogArchiver interface {
+	Create(ctx context.Context, name string) (ActionLogArchiveWriter, error)
+}
+
+// ActionLogArchiveWriter становится видимым в архиве только после Commit.
+type ActionLogArchiveWriter interface {
+	Write(ActionLog ActionLog) error
+	Commit() error
+	Abort() error
+}
+
+type ActionLogRetention struct {
+	storage  ActionLogPartitionStorage
+	archiver ActionLogArchiver
+	config   ActionLogRetentionConfig
+	clock    Clock
+
+	mu        sync.Mutex
+	scheduler *cron.Cron
+	stopped   bool
+}
+
+// NewActionLogRetention создаёт задачу хранения лога. Дни партиций и
+// расписание считаются по clock.
+func NewActionLogRetention(storage ActionLogPartitionStorage, archiver ActionLogArchiver, config ActionLogRetentionConfig, clock Clock) *ActionLogRetention {
+	return &ActionLogRetention{
+		storage:  storage,
+		archiver: archiver,
+		config:   config,
+		clock:    clock,
+	}
+}
+
+func (rr *ActionLogRetention) InitActionLogRetention(ctx context.Context) error {
+	op := "internal.ActionLogRetention.InitActionLogRetention"
+
+	err := rr.RunRetention(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = rr.cronRetention(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RunRetention создаёт партиции на ближайшие дни и убирает партиции старше
+// срока хранения, предварительно выгружая их в архив, если это настроено.
+// Ошибка одной партиции не останавливает обработку остальных, все ошибки
+// возвращаются вместе.
+func (rr *ActionLogRetention) RunRetention(ctx context.Context) error {
+	op := "internal.ActionLogRetention.RunRetention"
+	today := rr.clock.Date(rr.clock.Now())
+
+	for day := 0; day <= rr.config.PrecreateDays; day++ {
+		err := rr.storage.CreatePartition(ctx, today.AddDate(0, 0, day))
+		if err != nil {
+			slog.WarnContext(ctx, "creating action log partition failed", "op", op, "error", err)
+		}
+	}
+
+	partitions, err := rr.storage.ListPartitions(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	cutoff := today.AddDate(0, 0, -rr.config.RetentionDays)
+	var errs []error
+	for _, partition := range partitions {
+		if !partition.IsDefault && partition.To.After(cutoff) {
+			continue
+		}
+
+		err := rr.expirePartition(ctx, partition, cutoff)
+		if err != nil {
+			slog.WarnContext(ctx, "expiring action log partition failed", "op", op, "partition", partition.Name, "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (rr *ActionLogRetention) expirePartition(ctx context.Context, partition ActionLogPartition, cutoff time.Time) error {
+	op := "internal.ActionLogRetention.expirePartition"
+
+	if rr.config.Mode == ActionLogRetentionArchive {
+		name := partition.Name
+		if partition.IsDefault {
+			// Партиция по умолчанию выгружается при каждом запуске, поэтому в
+			// имени есть момент запуска, чтобы архивы не совпадали по имени.
+			name = fmt.Sprintf("%s_before_%s_at_%s", partition.Name, cutoff.Format("20060102"), rr.clock.Now().UTC().Format("20060102T150405"))
+		}
+
+		err := rr.archivePartition(ctx, partition, name, cutoff)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if partition.IsDefault {
+		err := rr.storage.DeleteFromPartition(ctx, partition, cutoff)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+
+	err := rr.storage.DropPartition(ctx, partition)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.InfoContext(ctx, "action log partition expired", "partition", partition.Name, "mode", rr.config.Mode)
+	return nil
+}
+
+func (rr *ActionLogRetention) archivePartition(ctx context.Context, partition ActionLogPartition, name string, before time.Time) error {
+	op := "internal.ActionLogRetention.archivePartition"
+
+	if rr.archiver == nil {
+		return fmt.Errorf("%s: Не настроено хранилище архива логов", op)
+	}
+
+	writer, err := rr.archiver.Create(ctx, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = rr.storage.ReadPartition(ctx, partition, before, writer.Write)
+	if err != nil {
+		if abortErr := writer.Abort(); abortErr != nil {
+			slog.ErrorContext(ctx, "aborting action log archive failed", "op", op, "error", abortErr)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = writer.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (rr *ActionLogRetention) cronRetention(ctx context.Context) error {
+	op := "internal.ActionLogRetention.cronRetention"
+	scheduler := cron.New(cron.WithLocation(rr.clock.Location()))
+
+	_, err := scheduler.AddFunc(rr.config.Schedule, func() {
+		err := rr.RunRetention(ctx)
+		metrics.ObserveSchedulerRun("action_log_retention", err)
+		if err != nil {
+			slog.ErrorContext(ctx, "action log retention run failed", "op", op, "error", err)
+		}
+	})
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if rr.stopped {
+		return nil
+	}
+
+	rr.scheduler = scheduler
+	scheduler.Start()
+
+	return nil
+}
+
+func (rr *ActionLogRetention) Stop(ctx context.Context) error {
+	op := "internal.ActionLogRetention.Stop"
+
+	rr.mu.Lock()
+	scheduler := rr.scheduler
+	rr.scheduler = nil
+	rr.stopped = true
+	rr.mu.Unlock()
+
+	if scheduler == nil {
+		return nil
+	}
+
+	select {
+	case <-scheduler.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
@@ -2,31 +2,118 @@ package internal
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"sync"
 )
 
-var BaseActionLogType = map[string]struct{}{
-	"PAIR": {},
-	"DATE": {},
-}
+// Тип действия задаётся именем вида "<namespace>.<event>", например
+// "rate.pair" или "admin.key_rotate". Новые типы регистрируются через
+// RegisterActionLogType.
+var actionLogTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)+$`)
+
+const actionLogTypeMaxLen int = 64
+
+var (
+	actionLogTypesMu sync.RWMutex
+	actionLogTypes   = map[string]struct{}{}
+)
+
+var (
+	ActionLogRatePair           = mustRegisterActionLogType("rate.pair")
+	ActionLogRateDate           = mustRegisterActionLogType("rate.date")
+	ActionLogRateMatrix         = mustRegisterActionLogType("rate.matrix")
+	ActionLogRateTimeSeries     = mustRegisterActionLogType("rate.timeseries")
+	ActionLogRateConvert        = mustRegisterActionLogType("rate.convert")
+	ActionLogRateFluctuation    = mustRegisterActionLogType("rate.fluctuation")
+	ActionLogRateStats          = mustRegisterActionLogType("rate.stats")
+	ActionLogRateOHLC           = mustRegisterActionLogType("rate.ohlc")
+	ActionLogRateStream         = mustRegisterActionLogType("rate.stream")
+	ActionLogSubscriptionCreate = mustRegisterActionLogType("subscription.create")
+	ActionLogSubscriptionDelete = mustRegisterActionLogType("subscription.delete")
+	ActionLogAdminKeyCreate     = mustRegisterActionLogType("admin.key_create")
+	ActionLogAdminKeyRotate     = mustRegisterActionLogType("admin.key_rotate")
+	ActionLogJobRun             = mustRegisterActionLogType("job.run")
+	ActionLogLogQuery           = mustRegisterActionLogType("log.query")
+)
 
 type ActionLogType struct {
 	Action string
 }
 
+func (t ActionLogType) Namespace() string {
+	namespace, _, _ := strings.Cut(t.Action, ".")
+	return namespace
+}
+
+func (t ActionLogType) String() string {
+	return t.Action
+}
+
+func RegisterActionLogType(action string) (ActionLogType, error) {
+	op := "internal.logDbType.RegisterActionLogType"
+	action = strings.ToLower(strings.TrimSpace(action))
+
+	if err := validateActionLogTypeName(action); err != nil {
+		return ActionLogType{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	actionLogTypesMu.Lock()
+	actionLogTypes[action] = struct{}{}
+	actionLogTypesMu.Unlock()
+
+	return ActionLogType{Action: action}, nil
+}
+
 func NewActionLogType(action string) (ActionLogType, error) {
 	op := "internal.logDbType.NewActionLogType"
-	action = strings.ToUpper(strings.TrimSpace(action))
+	action = strings.ToLower(strings.TrimSpace(action))
 
-	if len(action) != 4 {
-		err := fmt.Sprintf("Название действия должно состоять из 4 символов")
-		return ActionLogType{}, fmt.Errorf("%s: %s", op, err)
+	if err := validateActionLogTypeName(action); err != nil {
+		return ActionLogType{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if _, ok := BaseActionLogType[action]; !ok {
-		err := fmt.Sprintf("Отсутствует такое действие в системе: %s", action)
-		return ActionLogType{}, fmt.Errorf("%s: %s", op, err)
+	actionLogTypesMu.RLock()
+	_, ok := actionLogTypes[action]
+	actionLogTypesMu.RUnlock()
+
+	if !ok {
+		err := ErrUnknownActionType.With(map[string]any{"type": action})
+		return ActionLogType{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return ActionLogType{Action: action}, nil
 }
+
+func ActionLogTypes() []ActionLogType {
+	actionLogTypesMu.RLock()
+	defer actionLogTypesMu.RUnlock()
+
+	result := make([]ActionLogType, 0, len(actionLogTypes))
+	for action := range actionLogTypes {
+		result = append(result, ActionLogType{Action: action})
+	}
+
+	return result
+}
+
+func validateActionLogTypeName(action string) error {
+	if len(action) > actionLogTypeMaxLen {
+		return ErrInvalidActionType.With(map[string]any{"type": action, "max_length": actionLogTypeMaxLen})
+	}
+
+	if !actionLogTypePattern.MatchString(action) {
+		return ErrInvalidActionType.With(map[string]any{"type": action})
+	}
+
+	return nil
+}
+
+func mustRegisterActionLogType(action string) ActionLogType {
+	actionLogType, err := RegisterActionLogType(action)
+	if err != nil {
+		panic(err)
+	}
+
+	return actionLogType
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BusinessCalendar знает выходные и праздники рынков валют. День рабочий,
+// только если он рабочий для всех переданных валют.
+type BusinessCalendar interface {
+	IsBusinessDay(date time.Time, currencies ...string) bool
+	PreviousBusinessDay(date time.Time, currencies ...string) time.Time
+}
+
+// DateMode определяет, что делать с запросом курса на нерабочий день.
+type DateMode string
+
+const (
+	// DatePrevious подставляет курс предыдущего рабочего дня.
+	DatePrevious DateMode = "previous"
+	// DateStrict возвращает ErrNotBusinessDay.
+	DateStrict DateMode = "strict"
+)
+
+func ParseDateMode(mode string) (DateMode, error) {
+	op := "internal.BusinessDay.ParseDateMode"
+
+	switch parsed := DateMode(strings.ToLower(strings.TrimSpace(mode))); parsed {
+	case DatePrevious, DateStrict:
+		return parsed, nil
+	case "":
+		return DatePrevious, nil
+	}
+
+	err := ErrInvalidArgument.With(map[string]any{"mode": mode})
+	return "", fmt.Errorf("%s: %w", op, err)
+}
+
+// RatesOnDate - курсы, запрошенные на Date. Дата каждого курса - рабочий
+// день его пары. EffectiveDate - самая поздняя из этих дат; совпадает с Date,
+// если он рабочий для всех пар.
+type RatesOnDate struct {
+	Date          time.Time
+	EffectiveDate time.Time
+	Exchanges     []Exchange
+}
+
+// resolveBusinessDate возвращает рабочий день, курсы которого отдаются на
+// date для пар из currencies.
+func (rr *ExchangeRepository) resolveBusinessDate(date time.Time, mode DateMode, currencies []string) (time.Time, error) {
+	op := "internal.BusinessDay.resolveBusinessDate"
+
+	if rr.calendar == nil || rr.calendar.IsBusinessDay(date, currencies...) {
+		return date, nil
+	}
+
+	if mode == DateStrict {
+		err := ErrNotBusinessDay.With(map[string]any{"date": date.Format(dataFormat)})
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rr.calendar.PreviousBusinessDay(date, currencies...), nil
+}
+
+// businessTargets оставляет из targetCurrencyCodes валюты, для пары с
+// которыми date - рабочий день.
+func (rr *ExchangeRepository) businessTargets(date time.Time, baseCurrencyCode string, targetCurrencyCodes []string) []string {
+	if rr.calendar == nil {
+		return targetCurrencyCodes
+	}
+
+	targets := make([]string, 0, len(targetCurrencyCodes))
+	for _, code := range targetCurrencyCodes {
+		if rr.calendar.IsBusinessDay(date, baseCurrencyCode, code) {
+			targets = append(targets, code)
+		}
+	}
+
+	return targets
+}
+
+// supportedCurrencies возвращает все валюты, курсы которых хранит сервис.
+func supportedCurrencies() []string {
+	currencies := make([]string, 0, len(defaultBase))
+	for code := range defaultBase {
+		currencies = append(currencies, code)
+	}
+	sort.Strings(currencies)
+
+	return currencies
+}
@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+)
+
+type CandleInterval string
+
+const (
+	CandleHour  CandleInterval = "hour"
+	CandleDay   CandleInterval = "day"
+	CandleWeek  CandleInterval = "week"
+	CandleMonth CandleInterval = "month"
+)
+
+// Candle - OHLC свеча курса пары за интервал [Start, End).
+type Candle struct {
+	BaseCurrency   Currency
+	TargetCurrency Currency
+	Interval       CandleInterval
+	Start          time.Time
+	End            time.Time
+	Open           float64
+	High           float64
+	Low            float64
+	Close          float64
+	// Count - число наблюдений курса, из которых собрана свеча.
+	Count int
+}
+
+// CandleStorage строит свечи по наблюдениям курса. Candles не учитывает
+// наблюдения, уже попавшие в сохранённые свёртки, поэтому для недель и
+// месяцев результат дополняется через Rollups.
+type CandleStorage interface {
+	Candles(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, interval CandleInterval, start, end time.Time) ([]Candle, error)
+	Rollups(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, interval CandleInterval, start, end time.Time) ([]Candle, error)
+	SetRollups(ctx context.Context, candles []Candle) error
+}
+
+func ParseCandleInterval(interval string) (CandleInterval, error) {
+	op := "internal.Candle.ParseCandleInterval"
+
+	switch parsed := CandleInterval(strings.ToLower(strings.TrimSpace(interval))); parsed {
+	case CandleHour, CandleDay, CandleWeek, CandleMonth:
+		return parsed, nil
+	case "":
+		return CandleDay, nil
+	}
+
+	err := ErrInvalidArgument.With(map[string]any{"interval": interval})
+	return "", fmt.Errorf("%s: %w", op, err)
+}
+
+// Truncate возвращает начало интервала, в который попадает t. Недели
+// начинаются с понедельника, все границы считаются в UTC.
+func (i CandleInterval) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+
+	switch i {
+	case CandleHour:
+		return t.Truncate(time.Hour)
+	case CandleWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case CandleMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Next возвращает начало интервала, следующего за тем, который начинается в
+// start.
+func (i CandleInterval) Next(start time.Time) time.Time {
+	switch i {
+	case CandleHour:
+		return start.Add(time.Hour)
+	case CandleWeek:
+		return start.AddDate(0, 0, 7)
+	case CandleMonth:
+		return start.AddDate(0, 1, 0)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
+
+// rollup означает, что закрытые свечи интервала сохраняются в бд.
+func (i CandleInterval) rollup() bool {
+	return i == CandleWeek || i == CandleMonth
+}
+
+// GetCandles строит свечи base/target за период с start по end включительно.
+// Период расширяется до границ интервалов, чтобы первая и последняя свечи
+// были полными. Закрытые недельные и месячные свечи берутся из свёрток, а
+// недостающие считаются по наблюдениям и сохраняются.
+func (rr *ExchangeRepository) GetCandles(ctx context.Context, baseCurrencyCode, targetCurrencyCode, interval, start, end string) ([]Candle, error) {
+	op := "internal.Exchange.GetCandles"
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrencyKey.String(targetCurrencyCode),
+		tracing.DateKey.String(start+"/"+end))
+	defer span.End()
+
+	candleInterval, err := ParseCandleInterval(interval)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	baseCurrency, targetCurrencies, err := newCurrencyPairs(baseCurrencyCode, []string{targetCurrencyCode})
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+	targetCurrency := targetCurrencies[0]
+
+	startDate, endDate, err := rr.parseDateRange(start, end)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	from := candleInterval.Truncate(startDate)
+	to := candleInterval.Next(candleInterval.Truncate(endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)))
+
+	var rollups []Candle
+	if candleInterval.rollup() {
+		rollups, err = rr.candles.Rollups(ctx, baseCurrency.Code, targetCurrency.Code, candleInterval, from, to)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+	}
+
+	live, err := rr.candles.Candles(ctx, baseCurrency.Code, targetCurrency.Code, candleInterval, from, to)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if candleInterval.rollup() {
+		now := rr.clock.Now()
+		closed := make([]Candle, 0, len(live))
+		for _, candle := range live {
+			if !candle.End.After(now) {
+				closed = append(closed, candle)
+			}
+		}
+
+		if len(closed) > 0 {
+			err = rr.candles.SetRollups(ctx, closed)
+			if err != nil {
+				return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+			}
+		}
+	}
+
+	return mergeCandles(rollups, live), nil
+}
+
+// mergeCandles объединяет два упорядоченных по времени списка свечей без
+// пересечений.
+func mergeCandles(a, b []Candle) []Candle {
+	result := make([]Candle, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0].Start.Before(b[0].Start) {
+			result = append(result, a[0])
+			a = a[1:]
+		} else {
+			result = append(result, b[0])
+			b = b[1:]
+		}
+	}
+	result = append(result, a...)
+
+	return append(result, b...)
+}
@@ -0,0 +1,17 @@
+package internal
+
+import "time"
+
+// Clock - источник текущего времени. Все расчёты дат курсов идут через него,
+// чтобы «сегодня» одинаково понималось в репозитории, бд и планировщике, а
+// в тестах время можно было подменить.
+type Clock interface {
+	Now() time.Time
+	// Location - часовой пояс, в котором работает планировщик.
+	Location() *time.Location
+	// Date возвращает календарную дату момента t как полночь UTC.
+	Date(t time.Time) time.Time
+	// RateDate возвращает дату курсов, которые были текущими в момент t,
+	// с учётом времени их публикации.
+	RateDate(t time.Time) time.Time
+}
@@ -21,13 +21,13 @@ func NewCurrency(code string) (Currency, error) {
 	code = strings.ToUpper(strings.TrimSpace(code))
 
 	if len(code) != 3 {
-		err := fmt.Sprintf("Название действия должно состоять из 4 символов")
-		return Currency{}, fmt.Errorf("%s: %s", op, err)
+		err := ErrInvalidCurrencyCode.With(map[string]any{"currency": code})
+		return Currency{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if _, ok := defaultBase[code]; !ok {
-		err := fmt.Sprintf("Отсутствует такая валюта в системе: %s", code)
-		return Currency{}, fmt.Errorf("%s: %s", op, err)
+		err := ErrUnsupportedCurrency.With(map[string]any{"currency": code})
+		return Currency{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return Currency{Code: code}, nil
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// DatePolicy проверяет даты курсов до обращения к стороннему апи: дата не
+// может быть позже текущей даты курсов и раньше первой даты, за которую у
+// провайдера есть курсы.
+type DatePolicy struct {
+	clock    Clock
+	earliest map[string]time.Time
+}
+
+// NewDatePolicy создаёт политику дат. earliest - первая дата с курсами по
+// имени провайдера; для провайдера без записи нижней границы нет.
+func NewDatePolicy(clock Clock, earliest map[string]time.Time) *DatePolicy {
+	return &DatePolicy{
+		clock:    clock,
+		earliest: earliest,
+	}
+}
+
+// Today возвращает текущую дату курсов по часам сервиса в том же виде, что и
+// ParseDate. До времени публикации это вчерашняя дата: курсы за сегодня ещё
+// не опубликованы.
+func (p *DatePolicy) Today() time.Time {
+	return p.clock.RateDate(p.clock.Now())
+}
+
+// CheckFuture возвращает ErrDateInFuture, если date позже текущей даты курсов.
+func (p *DatePolicy) CheckFuture(date time.Time) error {
+	op := "internal.DatePolicy.CheckFuture"
+
+	today := p.Today()
+	if date.After(today) {
+		err := ErrDateInFuture.With(map[string]any{"date": date.Format(dataFormat), "today": today.Format(dataFormat)})
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Check проверяет, что курс на date можно запросить у provider.
+func (p *DatePolicy) Check(provider string, date time.Time) error {
+	op := "internal.DatePolicy.Check"
+
+	err := p.CheckFuture(date)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	earliest, ok := p.earliest[provider]
+	if ok && date.Before(earliest) {
+		err := ErrDateTooEarly.With(map[string]any{
+			"date":     date.Format(dataFormat),
+			"earliest": earliest.Format(dataFormat),
+			"provider": provider,
+		})
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal/clock"
+)
+
+func TestDatePolicyCheckFuture(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 2024-03-04 10:00 по Москве, курсы публикуются в 12:00
+	fake := clock.NewFake(time.Date(2024, 3, 4, 7, 0, 0, 0, time.UTC), moscow, 12*time.Hour)
+	policy := NewDatePolicy(fake, nil)
+
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	sunday := monday.AddDate(0, 0, -1)
+
+	if err := policy.CheckFuture(sunday); err != nil {
+		t.Errorf("CheckFuture(%s) before cutoff error = %v", sunday.Format(dataFormat), err)
+	}
+	if err := policy.CheckFuture(monday); !errors.Is(err, ErrDateInFuture) {
+		t.Errorf("CheckFuture(%s) before cutoff error = %v, want ErrDateInFuture", monday.Format(dataFormat), err)
+	}
+
+	fake.Advance(3 * time.Hour)
+	if err := policy.CheckFuture(monday); err != nil {
+		t.Errorf("CheckFuture(%s) after cutoff error = %v", monday.Format(dataFormat), err)
+	}
+	if got := policy.Today(); !got.Equal(monday) {
+		t.Errorf("Today() = %s, want %s", got.Format(dataFormat), monday.Format(dataFormat))
+	}
+}
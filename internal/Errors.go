@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+)
+
+type ErrorCode string
+
+const (
+	CodeInvalidArgument      ErrorCode = "invalid_argument"
+	CodeMissingParameter     ErrorCode = "missing_parameter"
+	CodeInvalidCurrencyCode  ErrorCode = "invalid_currency_code"
+	CodeSameCurrency         ErrorCode = "same_currency"
+	CodeInvalidRate          ErrorCode = "invalid_rate"
+	CodeInvalidAmount        ErrorCode = "invalid_amount"
+	CodeInvalidActionType    ErrorCode = "invalid_action_type"
+	CodeUnknownActionType    ErrorCode = "unknown_action_type"
+	CodeInvalidTimeRange     ErrorCode = "invalid_time_range"
+	CodeRangeTooLarge        ErrorCode = "range_too_large"
+	CodeUnsupportedFormat    ErrorCode = "unsupported_format"
+	CodeInvalidDate          ErrorCode = "invalid_date"
+	CodeUnsupportedCurrency  ErrorCode = "unsupported_currency"
+	CodeDateInFuture         ErrorCode = "date_in_future"
+	CodeDateTooEarly         ErrorCode = "date_too_early"
+	CodeNotBusinessDay       ErrorCode = "not_business_day"
+	CodeNotFound             ErrorCode = "not_found"
+	CodeSubscriptionNotFound ErrorCode = "subscription_not_found"
+	CodeInvalidWebhookURL    ErrorCode = "invalid_webhook_url"
+	CodeUnauthorized         ErrorCode = "unauthorized"
+	CodeUpstreamError        ErrorCode = "upstream_error"
+	CodeUpstreamUnavailable  ErrorCode = "upstream_unavailable"
+	CodeInternal             ErrorCode = "internal"
+)
+
+// Error - ошибка предметной области с устойчивым кодом. Текста для клиента
+// в ней нет: сообщение по коду и Details подбирает пакет i18n. Ошибки
+// сравниваются по коду, поэтому errors.Is(err, ErrNotFound) срабатывает для
+// любой ошибки с кодом not_found, в том числе обёрнутой через %w.
+type Error struct {
+	Code    ErrorCode
+	Details map[string]any
+}
+
+var (
+	ErrInvalidArgument      = &Error{Code: CodeInvalidArgument}
+	ErrMissingParameter     = &Error{Code: CodeMissingParameter}
+	ErrInvalidCurrencyCode  = &Error{Code: CodeInvalidCurrencyCode}
+	ErrSameCurrency         = &Error{Code: CodeSameCurrency}
+	ErrInvalidRate          = &Error{Code: CodeInvalidRate}
+	ErrInvalidAmount        = &Error{Code: CodeInvalidAmount}
+	ErrInvalidActionType    = &Error{Code: CodeInvalidActionType}
+	ErrUnknownActionType    = &Error{Code: CodeUnknownActionType}
+	ErrInvalidTimeRange     = &Error{Code: CodeInvalidTimeRange}
+	ErrRangeTooLarge        = &Error{Code: CodeRangeTooLarge}
+	ErrUnsupportedFormat    = &Error{Code: CodeUnsupportedFormat}
+	ErrInvalidDate          = &Error{Code: CodeInvalidDate}
+	ErrUnsupportedCurrency  = &Error{Code: CodeUnsupportedCurrency}
+	ErrDateInFuture         = &Error{Code: CodeDateInFuture}
+	ErrDateTooEarly         = &Error{Code: CodeDateTooEarly}
+	ErrNotBusinessDay       = &Error{Code: CodeNotBusinessDay}
+	ErrNotFound             = &Error{Code: CodeNotFound}
+	ErrSubscriptionNotFound = &Error{Code: CodeSubscriptionNotFound}
+	ErrInvalidWebhookURL    = &Error{Code: CodeInvalidWebhookURL}
+	ErrUnauthorized         = &Error{Code: CodeUnauthorized}
+	ErrUpstreamError        = &Error{Code: CodeUpstreamError}
+	ErrUpstreamUnavailable  = &Error{Code: CodeUpstreamUnavailable}
+)
+
+func (e *Error) Error() string {
+	if len(e.Details) == 0 {
+		return string(e.Code)
+	}
+
+	return fmt.Sprintf("%s %v", e.Code, e.Details)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// With возвращает копию ошибки с подробностями.
+func (e *Error) With(details map[string]any) *Error {
+	result := &Error{Code: e.Code}
+	if len(details) > 0 {
+		result.Details = maps.Clone(details)
+	}
+
+	return result
+}
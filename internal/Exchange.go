@@ -3,10 +3,18 @@ package internal
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/robfig/cron/v3"
+	"github.com/sashaem1/ExchangeRate/internal/analytics"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
 )
 
 type ExchangeID string
@@ -17,35 +25,92 @@ type Exchange struct {
 	TargetCurrency Currency
 	Rate           float64
 	Timestamp      time.Time
+	// FetchedAt - момент, когда курс был получен от стороннего апи.
+	FetchedAt time.Time
+}
+
+// Pair возвращает пару валют в виде "USD/EUR".
+func (e Exchange) Pair() string {
+	return e.BaseCurrency.Code + "/" + e.TargetCurrency.Code
+}
+
+// ObservedAt - момент, к которому относится курс при построении свечей.
+// Курс, полученный, пока он был текущим, относится ко времени получения, а
+// курс за прошедшую дату - к началу этой даты.
+func (e Exchange) ObservedAt(clock Clock) time.Time {
+	day := clock.Date(e.Timestamp)
+	if !e.FetchedAt.IsZero() && clock.RateDate(e.FetchedAt).Equal(day) {
+		return e.FetchedAt.UTC()
+	}
+
+	return day
+}
+
+// ParsePairs проверяет пары валют вида "USD/EUR" и приводит их к верхнему
+// регистру; повторы отбрасываются.
+func ParsePairs(pairs []string) ([]string, error) {
+	op := "internal.Exchange.ParsePairs"
+
+	result := make([]string, 0, len(pairs))
+	seen := make(map[string]struct{}, len(pairs))
+	for _, pair := range pairs {
+		baseCode, targetCode, ok := strings.Cut(pair, "/")
+		if !ok {
+			err := ErrInvalidArgument.With(map[string]any{"pair": pair})
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		baseCurrency, targetCurrencies, err := newCurrencyPairs(baseCode, []string{targetCode})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		normalized := baseCurrency.Code + "/" + targetCurrencies[0].Code
+		if _, ok := seen[normalized]; ok {
+			continue
+		}
+		seen[normalized] = struct{}{}
+		result = append(result, normalized)
+	}
+
+	return result, nil
 }
 
 const dataFormat string = "2006-01-02"
 const cronUpdateTime string = "00 12 * * *"
 
+// defaultObserveSchedule - расписание опроса текущих курсов в течение дня по
+// умолчанию, из этих наблюдений строятся часовые свечи.
+const defaultObserveSchedule string = "0 * * * *"
+
+// ObserveScheduleOff отключает опрос текущих курсов в течение дня.
+const ObserveScheduleOff string = "off"
+const maxRangeDays int = 366
+
 var initDates []time.Time = []time.Time{
 	time.Date(2025, time.July, 21, 0, 0, 0, 0, time.UTC),
 	time.Date(2025, time.July, 22, 0, 0, 0, 0, time.UTC),
 	time.Date(2025, time.July, 23, 0, 0, 0, 0, time.UTC),
 	time.Date(2025, time.July, 24, 0, 0, 0, 0, time.UTC),
 	time.Date(2025, time.July, 25, 0, 0, 0, 0, time.UTC),
-	time.Now(),
 }
 
 func NewExchange(baseCurrencyCode, targetCurrencyCode string, rate float64, timestamp time.Time) (Exchange, error) {
 	op := "internal.Exchange.NewExchange"
 
 	if rate <= 0.0 {
-		return Exchange{}, fmt.Errorf("%s: Значение курса должно быть положительным", op)
+		err := ErrInvalidRate.With(map[string]any{"rate": rate})
+		return Exchange{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	baseCurrency, err := NewCurrency(baseCurrencyCode)
 	if err != nil {
-		return Exchange{}, fmt.Errorf("%s: %s", op, err)
+		return Exchange{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	targetCurrency, err := NewCurrency(targetCurrencyCode)
 	if err != nil {
-		return Exchange{}, fmt.Errorf("%s: %s", op, err)
+		return Exchange{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	newExchange := Exchange{
@@ -57,121 +122,822 @@ func NewExchange(baseCurrencyCode, targetCurrencyCode string, rate float64, time
 	return newExchange, nil
 }
 
+// ParseDate разбирает дату в формате "2006-01-02".
+func ParseDate(date string) (time.Time, error) {
+	parsedDate, err := time.Parse(dataFormat, date)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate.With(map[string]any{"date": date})
+	}
+
+	return parsedDate, nil
+}
+
 type ExchangeStorage interface {
 	Get(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, date time.Time) (Exchange, error)
-	Set(ctx context.Context, exchange Exchange) error
+	// GetPrevious возвращает последний сохранённый курс пары до date или
+	// пустой Exchange, если его нет.
+	GetPrevious(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, date time.Time) (Exchange, error)
+	GetMany(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, date time.Time) ([]Exchange, error)
+	GetRange(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, start, end time.Time) ([]Exchange, error)
+	// Set возвращает true, если курс добавлен или изменился.
+	Set(ctx context.Context, exchange Exchange) (bool, error)
+	LatestTimestamp(ctx context.Context) (time.Time, error)
 }
 
 type ExchangeExternalAPI interface {
-	GetByBase(baseCurrencyCode, targetCurrencyCode string) (Exchange, error)
-	GetByDate(baseCurrencyCode string, targetCurrencyCode []string, date time.Time) ([]Exchange, error)
+	// Name - имя провайдера, по нему DatePolicy находит первую дату с курсами.
+	Name() string
+	GetLatest(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string) ([]Exchange, error)
+	GetByDate(ctx context.Context, baseCurrencyCode string, targetCurrencyCode []string, date time.Time) ([]Exchange, error)
+}
+
+// ExchangePublisher получает курсы, которые были добавлены или изменились в
+// бд. Тема - пара валют вида "USD/EUR".
+type ExchangePublisher interface {
+	Publish(topic string, exchange Exchange)
 }
 
 type ExchangeRepository struct {
 	storage     ExchangeStorage
+	candles     CandleStorage
 	externalAPI ExchangeExternalAPI
+	publisher   ExchangePublisher
+	calendar    BusinessCalendar
+	clock       Clock
+	dates       *DatePolicy
+	// observeSchedule - расписание опроса текущих курсов, пустое - опрос отключён
+	observeSchedule string
+
+	mu            sync.Mutex
+	scheduler     *cron.Cron
+	stopped       bool
+	initStatus    ExchangeInitStatus
+	cancelSeeding context.CancelFunc
+	seedingDone   chan struct{}
 }
 
-func NewExchangeRepository(storage ExchangeStorage, externalAPI ExchangeExternalAPI) *ExchangeRepository {
+// NewExchangeRepository создаёт репозиторий курсов. publisher может быть
+// nil, если уведомления об изменениях не нужны, calendar - если все дни
+// считаются рабочими. Без dates даты проверяются только на будущее.
+func NewExchangeRepository(storage ExchangeStorage, candles CandleStorage, externalAPI ExchangeExternalAPI, publisher ExchangePublisher, calendar BusinessCalendar, clock Clock, dates *DatePolicy) *ExchangeRepository {
+	if dates == nil {
+		dates = NewDatePolicy(clock, nil)
+	}
+
 	return &ExchangeRepository{
 		storage:     storage,
+		candles:     candles,
 		externalAPI: externalAPI,
+		publisher:   publisher,
+		calendar:    calendar,
+		clock:       clock,
+		dates:       dates,
+		initStatus:  ExchangeInitStatus{State: ExchangeInitPending},
+
+		observeSchedule: defaultObserveSchedule,
 	}
 }
 
-func (rr *ExchangeRepository) GetByBase(baseCurrencyCode, targetCurrencyCode string) (Exchange, error) {
+// SetObserveSchedule задаёт cron расписание опроса текущих курсов в течение
+// дня. Пустое расписание оставляет расписание по умолчанию, ObserveScheduleOff
+// отключает опрос. Вызывается до InitExchangeRepository.
+func (rr *ExchangeRepository) SetObserveSchedule(schedule string) error {
+	op := "internal.Exchange.SetObserveSchedule"
+
+	schedule = strings.TrimSpace(schedule)
+	switch strings.ToLower(schedule) {
+	case "":
+		rr.observeSchedule = defaultObserveSchedule
+		return nil
+	case ObserveScheduleOff:
+		rr.observeSchedule = ""
+		return nil
+	}
+
+	_, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return fmt.Errorf("%s: invalid schedule %q: %w", op, schedule, err)
+	}
+	rr.observeSchedule = schedule
+
+	return nil
+}
+
+func (rr *ExchangeRepository) GetByBase(ctx context.Context, baseCurrencyCode, targetCurrencyCode string) (Exchange, error) {
 	op := "internal.Exchange.GetByBase"
-	ctx := context.Background()
 
-	exchange, err := rr.storage.Get(ctx, baseCurrencyCode, targetCurrencyCode, time.Now())
+	exchanges, err := rr.GetLatest(ctx, baseCurrencyCode, []string{targetCurrencyCode})
+	if err != nil {
+		return Exchange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exchanges[0], nil
+}
+
+// GetLatest возвращает текущие курсы base ко всем targetCurrencyCodes, а при
+// пустом списке - ко всем поддерживаемым валютам. Текущими считаются курсы
+// за Clock.RateDate. Курсы, которых нет в бд, запрашиваются у стороннего апи
+// одним запросом. Если дата курсов - выходной или праздник рынка одной из
+// валют пары, отдаётся курс её предыдущего рабочего дня: курсы на нерабочие
+// дни не запрашиваются и не сохраняются. Порядок результата совпадает с
+// порядком запрошенных валют.
+func (rr *ExchangeRepository) GetLatest(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string) ([]Exchange, error) {
+	op := "internal.Exchange.GetLatest"
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrenciesKey.StringSlice(targetCurrencyCodes))
+	defer span.End()
+
+	baseCurrency, targetCurrencies, err := newCurrencyPairs(baseCurrencyCode, targetCurrencyCodes)
 	if err != nil {
-		return Exchange{}, fmt.Errorf("%s: %s", op, err)
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	targetCodes := make([]string, 0, len(targetCurrencies))
+	for _, currency := range targetCurrencies {
+		targetCodes = append(targetCodes, currency.Code)
+	}
+
+	rateDate := rr.clock.RateDate(rr.clock.Now())
+	currentCodes := rr.businessTargets(rateDate, baseCurrency.Code, targetCodes)
+	stored, err := rr.storage.GetMany(ctx, baseCurrency.Code, currentCodes, rateDate)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	byTarget := make(map[string]Exchange, len(targetCodes))
+	for _, exchange := range stored {
+		byTarget[exchange.TargetCurrency.Code] = exchange
 	}
 
-	if exchange.Timestamp.IsZero() {
-		exchange, err = rr.externalAPI.GetByBase(baseCurrencyCode, targetCurrencyCode)
+	var missing []string
+	for _, code := range currentCodes {
+		if _, ok := byTarget[code]; !ok {
+			missing = append(missing, code)
+		}
+	}
+
+	metrics.ObserveCacheLookups("exchange", len(currentCodes)-len(missing), len(missing))
+	span.SetAttributes(tracing.CacheOutcomeKey.String(cacheOutcome(len(currentCodes)-len(missing), len(missing))))
+
+	if len(missing) > 0 {
+		slog.DebugContext(ctx, "rates not stored, fetching from provider",
+			"base", baseCurrency.Code, "targets", missing)
+		fetched, err := rr.externalAPI.GetLatest(ctx, baseCurrency.Code, missing)
 		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		for _, exchange := range fetched {
+			exchange.Timestamp = rateDate
+			err = rr.store(ctx, exchange)
+			if err != nil {
+				return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+			}
+			byTarget[exchange.TargetCurrency.Code] = exchange
+		}
+	}
 
-			return Exchange{}, fmt.Errorf("%s: %s", op, err)
+	// Остальные пары группируются по своему предыдущему рабочему дню, как в
+	// GetByDate.
+	var previousDates []time.Time
+	previousTargets := make(map[time.Time][]string)
+	for _, code := range targetCodes {
+		if slices.Contains(currentCodes, code) {
+			continue
 		}
 
-		err = rr.storage.Set(ctx, exchange)
+		effectiveDate, err := rr.resolveBusinessDate(rateDate, DatePrevious, []string{baseCurrency.Code, code})
 		if err != nil {
-			return Exchange{}, fmt.Errorf("%s: %s", op, err)
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 		}
+		if _, ok := previousTargets[effectiveDate]; !ok {
+			previousDates = append(previousDates, effectiveDate)
+		}
+		previousTargets[effectiveDate] = append(previousTargets[effectiveDate], code)
 	}
 
-	return exchange, nil
+	for _, effectiveDate := range previousDates {
+		previous, err := rr.getOnDate(ctx, effectiveDate, map[string][]string{baseCurrency.Code: previousTargets[effectiveDate]})
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		for _, exchange := range previous {
+			byTarget[exchange.TargetCurrency.Code] = exchange
+		}
+	}
+
+	exchanges := make([]Exchange, 0, len(targetCodes))
+	for _, code := range targetCodes {
+		exchange, ok := byTarget[code]
+		if !ok {
+			err := ErrNotFound.With(map[string]any{"base": baseCurrency.Code, "target": code})
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+		exchanges = append(exchanges, exchange)
+	}
+
+	return exchanges, nil
 }
 
-func (rr *ExchangeRepository) GetByDate(date string) ([]Exchange, error) {
+// newCurrencyPairs проверяет base и список валют к ней. Пустой список
+// означает все валюты, поддерживаемые для base; повторы отбрасываются.
+func newCurrencyPairs(baseCurrencyCode string, targetCurrencyCodes []string) (Currency, []Currency, error) {
+	op := "internal.Exchange.newCurrencyPairs"
+
+	baseCurrency, err := NewCurrency(baseCurrencyCode)
+	if err != nil {
+		return Currency{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(targetCurrencyCodes) == 0 {
+		targetCurrencyCodes = defaultBase[baseCurrency.Code]
+	}
+
+	targetCurrencies := make([]Currency, 0, len(targetCurrencyCodes))
+	seen := make(map[Currency]struct{}, len(targetCurrencyCodes))
+	for _, code := range targetCurrencyCodes {
+		targetCurrency, err := NewCurrency(code)
+		if err != nil {
+			return Currency{}, nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if targetCurrency == baseCurrency {
+			err := ErrSameCurrency.With(map[string]any{"currency": baseCurrency.Code})
+			return Currency{}, nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if _, ok := seen[targetCurrency]; ok {
+			continue
+		}
+		seen[targetCurrency] = struct{}{}
+		targetCurrencies = append(targetCurrencies, targetCurrency)
+	}
+
+	return baseCurrency, targetCurrencies, nil
+}
+
+// GetByDate возвращает курсы всех пар на дату. Если дата - выходной или
+// праздник рынка одной из валют пары, для этой пары берётся её предыдущий
+// рабочий день, а в режиме DateStrict возвращается ErrNotBusinessDay; курсы
+// на нерабочие дни не запрашиваются у стороннего апи и не сохраняются.
+func (rr *ExchangeRepository) GetByDate(ctx context.Context, date string, mode DateMode) (RatesOnDate, error) {
 	op := "internal.Exchange.GetByDate"
-	ctx := context.Background()
+	ctx, span := tracing.Start(ctx, op, tracing.DateKey.String(date))
+	defer span.End()
 
-	exchanges := []Exchange{}
-	parsedDate, err := time.Parse(dataFormat, date)
+	parsedDate, err := ParseDate(date)
+	if err != nil {
+		return RatesOnDate{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	err = rr.dates.Check(rr.externalAPI.Name(), parsedDate)
 	if err != nil {
-		return exchanges, fmt.Errorf("%s: %s", op, err)
+		return RatesOnDate{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if mode == DateStrict {
+		_, err = rr.resolveBusinessDate(parsedDate, DateStrict, supportedCurrencies())
+		if err != nil {
+			return RatesOnDate{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+	}
+
+	// Пары группируются по своему рабочему дню, чтобы праздник одного рынка
+	// не сдвигал дату курсов остальных пар.
+	var effectiveDates []time.Time
+	pairsByDate := make(map[string]map[string][]string)
+	for _, baseCurrencyCode := range supportedCurrencies() {
+		for _, targetCurrencyCode := range defaultBase[baseCurrencyCode] {
+			effectiveDate, err := rr.resolveBusinessDate(parsedDate, DatePrevious, []string{baseCurrencyCode, targetCurrencyCode})
+			if err != nil {
+				return RatesOnDate{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+			}
+
+			key := effectiveDate.Format(dataFormat)
+			pairs, ok := pairsByDate[key]
+			if !ok {
+				// Предыдущий рабочий день может оказаться раньше первой даты провайдера
+				err = rr.dates.Check(rr.externalAPI.Name(), effectiveDate)
+				if err != nil {
+					return RatesOnDate{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+				}
+
+				pairs = make(map[string][]string)
+				pairsByDate[key] = pairs
+				effectiveDates = append(effectiveDates, effectiveDate)
+			}
+			pairs[baseCurrencyCode] = append(pairs[baseCurrencyCode], targetCurrencyCode)
+		}
 	}
 
-	exchanges, missingExchange, err := rr.getByDateFromDb(ctx, parsedDate)
+	rates := RatesOnDate{Date: parsedDate, Exchanges: []Exchange{}}
+	for _, effectiveDate := range effectiveDates {
+		exchanges, err := rr.getOnDate(ctx, effectiveDate, pairsByDate[effectiveDate.Format(dataFormat)])
+		if err != nil {
+			return RatesOnDate{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		rates.Exchanges = append(rates.Exchanges, exchanges...)
+		if effectiveDate.After(rates.EffectiveDate) {
+			rates.EffectiveDate = effectiveDate
+		}
+	}
+	span.SetAttributes(tracing.EffectiveDateKey.String(rates.EffectiveDate.Format(dataFormat)))
+
+	return rates, nil
+}
+
+// getOnDate возвращает курсы пар pairs на date из бд, догружая недостающие
+// из стороннего апи.
+func (rr *ExchangeRepository) getOnDate(ctx context.Context, date time.Time, pairs map[string][]string) ([]Exchange, error) {
+	op := "internal.Exchange.getOnDate"
+	ctx, span := tracing.Start(ctx, op, tracing.DateKey.String(date.Format(dataFormat)))
+	defer span.End()
+
+	exchanges, missingExchange, err := rr.getByDateFromDb(ctx, date, pairs)
 	if err != nil {
-		return exchanges, fmt.Errorf("%s: %s", op, err)
+		return exchanges, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	misses := 0
+	for _, targetCurrencyCodes := range missingExchange {
+		misses += len(targetCurrencyCodes)
 	}
+	metrics.ObserveCacheLookups("exchange", len(exchanges), misses)
+	span.SetAttributes(tracing.CacheOutcomeKey.String(cacheOutcome(len(exchanges), misses)))
 
 	if len(missingExchange) == 0 {
 		return exchanges, nil
 	} else {
-		exchanges, err = rr.getByDateFromExAPI(ctx, parsedDate)
+		slog.DebugContext(ctx, "rates missing for date, fetching from provider",
+			"date", date.Format(dataFormat), "missing", misses)
+		fetched, err := rr.getByDateFromExAPI(ctx, date, missingExchange)
 		if err != nil {
-			return exchanges, fmt.Errorf("%s: %s", op, err)
+			return exchanges, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 		}
 
-		err = rr.setByMisToDb(ctx, parsedDate, missingExchange, exchanges)
+		err = rr.setByMisToDb(ctx, date, missingExchange, fetched)
 		if err != nil {
-			return exchanges, fmt.Errorf("%s: %s", op, err)
+			return exchanges, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 		}
 
+		// Сторонний апи может вернуть больше валют, чем запрошено: курсы
+		// остальных пар относятся к другому рабочему дню.
+		for _, exchange := range fetched {
+			if slices.Contains(missingExchange[exchange.BaseCurrency.Code], exchange.TargetCurrency.Code) {
+				exchanges = append(exchanges, exchange)
+			}
+		}
 	}
 
 	return exchanges, nil
 }
 
-func (rr *ExchangeRepository) getByDateFromDb(ctx context.Context, date time.Time) (exchanges []Exchange, missingExchange map[string][]string, err error) {
+// GetTimeSeries возвращает сохранённые курсы base к targetCurrencyCodes за
+// период с start по end включительно. Пустой список валют означает все
+// поддерживаемые валюты. Сторонний апи не запрашивается: дни, за которые
+// курсов нет в бд, в результат не попадают.
+func (rr *ExchangeRepository) GetTimeSeries(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, start, end string) ([]Exchange, error) {
+	op := "internal.Exchange.GetTimeSeries"
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrenciesKey.StringSlice(targetCurrencyCodes),
+		tracing.DateKey.String(start+"/"+end))
+	defer span.End()
+
+	baseCurrency, targetCurrencies, err := newCurrencyPairs(baseCurrencyCode, targetCurrencyCodes)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	startDate, endDate, err := rr.parseDateRange(start, end)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	targetCodes := make([]string, 0, len(targetCurrencies))
+	for _, currency := range targetCurrencies {
+		targetCodes = append(targetCodes, currency.Code)
+	}
+
+	exchanges, err := rr.storage.GetRange(ctx, baseCurrency.Code, targetCodes, startDate, endDate)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return exchanges, nil
+}
+
+// RateStats - статистика курса пары за период по сохранённым в бд дням.
+type RateStats struct {
+	BaseCurrency   Currency
+	TargetCurrency Currency
+	Start          time.Time
+	End            time.Time
+	analytics.Summary
+}
+
+// GetStats считает статистику курса base к target за период с start по end
+// включительно. Как и GetTimeSeries, берёт только курсы из бд; если за период
+// нет ни одного курса, возвращает ErrNotFound.
+func (rr *ExchangeRepository) GetStats(ctx context.Context, baseCurrencyCode, targetCurrencyCode, start, end string) (RateStats, error) {
+	op := "internal.Exchange.GetStats"
+
+	exchanges, err := rr.GetTimeSeries(ctx, baseCurrencyCode, []string{targetCurrencyCode}, start, end)
+	if err != nil {
+		return RateStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(exchanges) == 0 {
+		err := ErrNotFound.With(map[string]any{"base": baseCurrencyCode, "target": targetCurrencyCode, "start": start, "end": end})
+		return RateStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	values := make([]float64, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		values = append(values, exchange.Rate)
+	}
+
+	startDate, _ := ParseDate(start)
+	endDate, _ := ParseDate(end)
+
+	stats := RateStats{
+		BaseCurrency:   exchanges[0].BaseCurrency,
+		TargetCurrency: exchanges[0].TargetCurrency,
+		Start:          startDate,
+		End:            endDate,
+		Summary:        analytics.Summarize(values),
+	}
+
+	return stats, nil
+}
+
+// Fluctuation - изменение курса пары между двумя датами. StartDate и
+// EndDate - рабочие дни пары, курсы которых сравниваются; совпадают с
+// запрошенными датами, если те рабочие.
+type Fluctuation struct {
+	BaseCurrency   Currency
+	TargetCurrency Currency
+	StartDate      time.Time
+	EndDate        time.Time
+	StartRate      float64
+	EndRate        float64
+	Change         float64
+	ChangePercent  float64
+}
+
+// GetFluctuation сравнивает курсы base к targetCurrencyCodes на даты start и
+// end. Выходные и праздники заменяются предыдущим рабочим днём каждой пары.
+// Курсы берутся из бд одним запросом за период, а недостающие на границах
+// периода запрашиваются у стороннего апи и сохраняются, как в GetByDate.
+func (rr *ExchangeRepository) GetFluctuation(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, start, end string) ([]Fluctuation, error) {
+	op := "internal.Exchange.GetFluctuation"
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrenciesKey.StringSlice(targetCurrencyCodes),
+		tracing.DateKey.String(start+"/"+end))
+	defer span.End()
+
+	baseCurrency, targetCurrencies, err := newCurrencyPairs(baseCurrencyCode, targetCurrencyCodes)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	startDate, endDate, err := rr.parseDateRange(start, end)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	// Границы периода для каждой пары переносятся на её рабочие дни. Недостающие
+	// курсы на них запрашиваются у провайдера, поэтому обе даты проверяются
+	// на покрытие провайдера.
+	ends := make(map[string][2]time.Time, len(targetCurrencies))
+	rangeStart := startDate
+	for _, targetCurrency := range targetCurrencies {
+		var pairEnds [2]time.Time
+		for i, date := range []time.Time{startDate, endDate} {
+			effectiveDate, err := rr.resolveBusinessDate(date, DatePrevious, []string{baseCurrency.Code, targetCurrency.Code})
+			if err != nil {
+				return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+			}
+
+			err = rr.dates.Check(rr.externalAPI.Name(), effectiveDate)
+			if err != nil {
+				return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+			}
+			pairEnds[i] = effectiveDate
+		}
+
+		ends[targetCurrency.Code] = pairEnds
+		if pairEnds[0].Before(rangeStart) {
+			rangeStart = pairEnds[0]
+		}
+	}
+
+	targetCodes := make([]string, 0, len(targetCurrencies))
+	for _, currency := range targetCurrencies {
+		targetCodes = append(targetCodes, currency.Code)
+	}
+
+	stored, err := rr.storage.GetRange(ctx, baseCurrency.Code, targetCodes, rangeStart, endDate)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	// rates[дата][валюта] - курсы base на даты границ периода
+	rates := make(map[string]map[string]float64)
+	for _, exchange := range stored {
+		date := exchange.Timestamp.Format(dataFormat)
+		if rates[date] == nil {
+			rates[date] = make(map[string]float64)
+		}
+		rates[date][exchange.TargetCurrency.Code] = exchange.Rate
+	}
+
+	var missingDates []time.Time
+	missingByDate := make(map[string][]string)
+	hits, misses := 0, 0
+	for _, code := range targetCodes {
+		for _, date := range ends[code] {
+			key := date.Format(dataFormat)
+			if _, ok := rates[key][code]; ok {
+				hits++
+				continue
+			}
+			if slices.Contains(missingByDate[key], code) {
+				continue
+			}
+			if _, ok := missingByDate[key]; !ok {
+				missingDates = append(missingDates, date)
+			}
+			missingByDate[key] = append(missingByDate[key], code)
+			misses++
+		}
+	}
+	metrics.ObserveCacheLookups("exchange", hits, misses)
+	span.SetAttributes(tracing.CacheOutcomeKey.String(cacheOutcome(hits, misses)))
+
+	for _, date := range missingDates {
+		key := date.Format(dataFormat)
+		missingExchange := map[string][]string{baseCurrency.Code: missingByDate[key]}
+		fetched, err := rr.getByDateFromExAPI(ctx, date, missingExchange)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		err = rr.setByMisToDb(ctx, date, missingExchange, fetched)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		// Сторонний апи может вернуть больше валют, чем запрошено: берутся
+		// только недостающие.
+		if rates[key] == nil {
+			rates[key] = make(map[string]float64)
+		}
+		for _, exchange := range fetched {
+			if _, ok := rates[key][exchange.TargetCurrency.Code]; !ok && exchange.BaseCurrency == baseCurrency {
+				rates[key][exchange.TargetCurrency.Code] = exchange.Rate
+			}
+		}
+	}
+
+	result := make([]Fluctuation, 0, len(targetCurrencies))
+	for _, targetCurrency := range targetCurrencies {
+		pairEnds := ends[targetCurrency.Code]
+		startRate, okStart := rates[pairEnds[0].Format(dataFormat)][targetCurrency.Code]
+		endRate, okEnd := rates[pairEnds[1].Format(dataFormat)][targetCurrency.Code]
+		if !okStart || !okEnd {
+			err := ErrNotFound.With(map[string]any{"base": baseCurrency.Code, "target": targetCurrency.Code, "start": start, "end": end})
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		result = append(result, Fluctuation{
+			BaseCurrency:   baseCurrency,
+			TargetCurrency: targetCurrency,
+			StartDate:      pairEnds[0],
+			EndDate:        pairEnds[1],
+			StartRate:      startRate,
+			EndRate:        endRate,
+			Change:         endRate - startRate,
+			ChangePercent:  (endRate - startRate) / startRate * 100,
+		})
+	}
+
+	return result, nil
+}
+
+// parseDateRange разбирает границы периода и проверяет, что он не пустой,
+// не длиннее maxRangeDays и не заканчивается в будущем.
+func (rr *ExchangeRepository) parseDateRange(start, end string) (time.Time, time.Time, error) {
+	op := "internal.Exchange.parseDateRange"
+
+	startDate, err := ParseDate(start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	endDate, err := ParseDate(end)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if endDate.Before(startDate) {
+		err := ErrInvalidTimeRange.With(map[string]any{"start": start, "end": end})
+		return time.Time{}, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if endDate.Sub(startDate) > time.Duration(maxRangeDays)*24*time.Hour {
+		err := ErrRangeTooLarge.With(map[string]any{"max_days": maxRangeDays})
+		return time.Time{}, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = rr.dates.CheckFuture(endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return startDate, endDate, nil
+}
+
+// Conversion - результат пересчёта суммы из одной валюты в другую по курсу
+// из хранилища. Суммы по bid и ask считает PricingProfile.Convert.
+type Conversion struct {
+	From   Currency
+	To     Currency
+	Amount float64
+	Rate   float64
+	Result float64
+	Date   time.Time
+}
+
+// Convert пересчитывает amount из fromCurrencyCode в toCurrencyCode по курсу
+// на дату date или по текущему курсу, если дата пустая.
+func (rr *ExchangeRepository) Convert(ctx context.Context, fromCurrencyCode, toCurrencyCode string, amount float64, date string) (Conversion, error) {
+	op := "internal.Exchange.Convert"
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(fromCurrencyCode), tracing.TargetCurrencyKey.String(toCurrencyCode), tracing.DateKey.String(date))
+	defer span.End()
+
+	if !(amount > 0) || math.IsInf(amount, 1) {
+		err := ErrInvalidAmount.With(map[string]any{"amount": amount})
+		return Conversion{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	from, to, err := newCurrencyPairs(fromCurrencyCode, []string{toCurrencyCode})
+	if err != nil {
+		return Conversion{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	var exchange Exchange
+	if date == "" {
+		exchanges, err := rr.GetLatest(ctx, from.Code, []string{to[0].Code})
+		if err != nil {
+			return Conversion{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+		exchange = exchanges[0]
+	} else {
+		rates, err := rr.GetByDate(ctx, date, DatePrevious)
+		if err != nil {
+			return Conversion{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		found := false
+		for _, ex := range rates.Exchanges {
+			if ex.BaseCurrency == from && ex.TargetCurrency == to[0] {
+				exchange, found = ex, true
+				break
+			}
+		}
+		if !found {
+			err := ErrNotFound.With(map[string]any{"base": from.Code, "target": to[0].Code, "date": date})
+			return Conversion{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+	}
+
+	conversion := Conversion{
+		From:   from,
+		To:     to[0],
+		Amount: amount,
+		Rate:   exchange.Rate,
+		Result: amount * exchange.Rate,
+		Date:   exchange.Timestamp,
+	}
+
+	return conversion, nil
+}
+
+// RateMatrix - таблица кросс-курсов всех поддерживаемых валют на дату.
+// Rates[base][target] - стоимость base в target, на диагонали 1.
+type RateMatrix struct {
+	Date       time.Time
+	Currencies []string
+	Rates      map[string]map[string]float64
+}
+
+// GetMatrix собирает таблицу кросс-курсов на дату, пустая дата означает
+// сегодня. Недостающие курсы догружаются так же, как в GetByDate.
+func (rr *ExchangeRepository) GetMatrix(ctx context.Context, date string) (RateMatrix, error) {
+	op := "internal.Exchange.GetMatrix"
+
+	if date == "" {
+		date = rr.dates.Today().Format(dataFormat)
+	}
+
+	rates, err := rr.GetByDate(ctx, date, DatePrevious)
+	if err != nil {
+		return RateMatrix{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	matrix := RateMatrix{
+		Date:       rates.Date,
+		Currencies: make([]string, 0, len(defaultBase)),
+		Rates:      make(map[string]map[string]float64, len(defaultBase)),
+	}
+
+	for code := range defaultBase {
+		matrix.Currencies = append(matrix.Currencies, code)
+		matrix.Rates[code] = map[string]float64{code: 1}
+	}
+	sort.Strings(matrix.Currencies)
+
+	for _, exchange := range rates.Exchanges {
+		matrix.Rates[exchange.BaseCurrency.Code][exchange.TargetCurrency.Code] = exchange.Rate
+	}
+
+	return matrix, nil
+}
+
+// LatestRateTimestamp возвращает дату самого свежего курса в бд или нулевое
+// время, если курсов ещё нет.
+func (rr *ExchangeRepository) LatestRateTimestamp(ctx context.Context) (time.Time, error) {
+	op := "internal.Exchange.LatestRateTimestamp"
+
+	timestamp, err := rr.storage.LatestTimestamp(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return timestamp, nil
+}
+
+func (rr *ExchangeRepository) SchedulerRunning() bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	return rr.scheduler != nil
+}
+
+func cacheOutcome(hits, misses int) string {
+	switch {
+	case misses == 0:
+		return tracing.CacheHit
+	case hits == 0:
+		return tracing.CacheMiss
+	default:
+		return tracing.CachePartial
+	}
+}
+
+// getByDateFromDb возвращает сохранённые курсы пар pairs на date и пары,
+// курсов которых в бд нет.
+func (rr *ExchangeRepository) getByDateFromDb(ctx context.Context, date time.Time, pairs map[string][]string) (exchanges []Exchange, missingExchange map[string][]string, err error) {
 	op := "internal.Exchange.GetByDateFromDb"
 	exchanges = []Exchange{}
 	missingExchange = make(map[string][]string)
 
-	for baseCurrencyCode, targetCurrencyCodes := range defaultBase {
-		for _, tcc := range targetCurrencyCodes {
-			currentExchange, err := rr.storage.Get(ctx, baseCurrencyCode, tcc, date)
-			if err != nil {
-				return exchanges, missingExchange, fmt.Errorf("%s: %s", op, err)
-			}
+	for baseCurrencyCode, targetCurrencyCodes := range pairs {
+		stored, err := rr.storage.GetMany(ctx, baseCurrencyCode, targetCurrencyCodes, date)
+		if err != nil {
+			return exchanges, missingExchange, fmt.Errorf("%s: %w", op, err)
+		}
+
+		found := make(map[string]struct{}, len(stored))
+		for _, currentExchange := range stored {
+			found[currentExchange.TargetCurrency.Code] = struct{}{}
+			exchanges = append(exchanges, currentExchange)
+		}
 
-			if currentExchange.Timestamp.IsZero() {
+		for _, tcc := range targetCurrencyCodes {
+			if _, ok := found[tcc]; !ok {
 				missingExchange[baseCurrencyCode] = append(missingExchange[baseCurrencyCode], tcc)
-			} else {
-				exchanges = append(exchanges, currentExchange)
 			}
-
 		}
 	}
 
 	return exchanges, missingExchange, nil
 }
 
-func (rr *ExchangeRepository) getByDateFromExAPI(ctx context.Context, date time.Time) (exchanges []Exchange, err error) {
+// getByDateFromExAPI запрашивает курсы на дату по одному запросу на каждую
+// основную валюту из pairs.
+func (rr *ExchangeRepository) getByDateFromExAPI(ctx context.Context, date time.Time, pairs map[string][]string) (exchanges []Exchange, err error) {
 	op := "internal.Exchange.GetByDateFromExAPI"
+	ctx, span := tracing.Start(ctx, op, tracing.DateKey.String(date.Format(dataFormat)))
+	defer span.End()
 	exchanges = []Exchange{}
 
-	for baseCurrencyCode, targetCurrencyCodes := range defaultBase {
+	for baseCurrencyCode, targetCurrencyCodes := range pairs {
 
 		currentExchange, err := rr.externalAPI.GetByDate(
-			baseCurrencyCode, targetCurrencyCodes, date)
+			ctx, baseCurrencyCode, targetCurrencyCodes, date)
 
 		if err != nil {
-			return exchanges, fmt.Errorf("%s: %s", op, err)
+			return exchanges, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 		}
 
 		exchanges = append(exchanges, currentExchange...)
@@ -188,9 +954,9 @@ func (rr *ExchangeRepository) setByMisToDb(ctx context.Context, date time.Time,
 		if ok {
 			for _, mtcc := range misTarCurrencyCodes {
 				if exchange.TargetCurrency.Code == mtcc {
-					err := rr.storage.Set(ctx, exchange)
+					err := rr.store(ctx, exchange)
 					if err != nil {
-						return fmt.Errorf("%s: %s", op, err)
+						return fmt.Errorf("%s: %w", op, err)
 					}
 				}
 			}
@@ -200,35 +966,66 @@ func (rr *ExchangeRepository) setByMisToDb(ctx context.Context, date time.Time,
 	return nil
 }
 
-func (rr *ExchangeRepository) InitExchangeRepository(ctx context.Context) error {
-	op := "internal.Exchange.InitExchangeRepository"
+// store сохраняет курс и публикует его, если он новый или изменился.
+// Публикуются только курсы на текущую дату курсов: курсы за прошлые даты,
+// догруженные по запросу истории, обновлениями не являются.
+func (rr *ExchangeRepository) store(ctx context.Context, exchange Exchange) error {
+	op := "internal.Exchange.store"
 
-	err := rr.initData(ctx, initDates)
+	changed, err := rr.storage.Set(ctx, exchange)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	current := exchange.Timestamp.Equal(rr.clock.RateDate(rr.clock.Now()))
+	if changed && current && rr.publisher != nil {
+		rr.publisher.Publish(exchange.Pair(), exchange)
 	}
 
-	err = rr.сronUpdateData(ctx)
+	return nil
+}
+
+func (rr *ExchangeRepository) InitExchangeRepository(ctx context.Context) error {
+	op := "internal.Exchange.InitExchangeRepository"
+
+	err := rr.сronUpdateData(ctx)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	rr.startSeeding(append(initDates[:len(initDates):len(initDates)], rr.clock.RateDate(rr.clock.Now())))
+
 	return nil
 }
 
+// initData загружает и сохраняет курсы всех пар на initDates. Пары, для
+// которых дата нерабочая, пропускаются.
 func (rr *ExchangeRepository) initData(ctx context.Context, initDates []time.Time) error {
 	op := "internal.Exchange.initData"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
 	for _, date := range initDates {
-		exchanges, err := rr.getByDateFromExAPI(ctx, date)
+		pairs := make(map[string][]string, len(defaultBase))
+		for baseCurrencyCode, targetCurrencyCodes := range defaultBase {
+			targetCurrencyCodes = rr.businessTargets(date, baseCurrencyCode, targetCurrencyCodes)
+			if len(targetCurrencyCodes) > 0 {
+				pairs[baseCurrencyCode] = targetCurrencyCodes
+			}
+		}
+		if len(pairs) == 0 {
+			slog.DebugContext(ctx, "no business day pairs for date, skipping", "date", date.Format(dataFormat))
+			continue
+		}
+
+		exchanges, err := rr.getByDateFromExAPI(ctx, date, pairs)
 		if err != nil {
-			log.Printf("%s: %s", op, err)
-			return nil
+			return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 		}
 
-		err = rr.setByMisToDb(ctx, date, defaultBase, exchanges)
+		err = rr.setByMisToDb(ctx, date, pairs, exchanges)
 		if err != nil {
-			return fmt.Errorf("%s: %s", op, err)
+			return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 		}
 	}
 
@@ -237,26 +1034,116 @@ func (rr *ExchangeRepository) initData(ctx context.Context, initDates []time.Tim
 
 func (rr *ExchangeRepository) сronUpdateData(ctx context.Context) error {
 	op := "internal.Exchange.InitExchangeRepository"
-	cron := cron.New()
+	scheduler := cron.New(cron.WithLocation(rr.clock.Location()))
 
-	_, err := cron.AddFunc(cronUpdateTime, func() {
-		log.Printf("!Проверка крона! Время:", time.Now().Format(time.RFC3339))
+	_, err := scheduler.AddFunc(cronUpdateTime, func() {
+		slog.InfoContext(ctx, "scheduled rate update started")
 		initDates := []time.Time{
-			time.Now(),
+			rr.clock.RateDate(rr.clock.Now()),
 		}
 
 		err := rr.initData(ctx, initDates)
+		metrics.ObserveSchedulerRun("exchange_update", err)
 		if err != nil {
-			log.Printf("%s: %s", op, err)
+			slog.ErrorContext(ctx, "scheduled rate update failed", "op", op, "error", err)
 		}
 
 	})
 
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	cron.Start()
+	if rr.observeSchedule != "" {
+		_, err = scheduler.AddFunc(rr.observeSchedule, func() {
+			err := rr.observeLatest(ctx)
+			metrics.ObserveSchedulerRun("exchange_observe", err)
+			if err != nil {
+				slog.ErrorContext(ctx, "scheduled rate observation failed", "op", op, "error", err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if rr.stopped {
+		return nil
+	}
+
+	rr.scheduler = scheduler
+	scheduler.Start()
 
 	return nil
 }
+
+// observeLatest запрашивает текущие курсы всех пар и сохраняет их. Каждый
+// вызов добавляет наблюдение для свечей и обновляет курс за текущую дату
+// публикации. Пары, для которых эта дата нерабочая, пропускаются: курсы на
+// выходные и праздники не хранятся.
+func (rr *ExchangeRepository) observeLatest(ctx context.Context) error {
+	op := "internal.Exchange.observeLatest"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	rateDate := rr.clock.RateDate(rr.clock.Now())
+	for baseCurrencyCode, targetCurrencyCodes := range defaultBase {
+		targetCurrencyCodes = rr.businessTargets(rateDate, baseCurrencyCode, targetCurrencyCodes)
+		if len(targetCurrencyCodes) == 0 {
+			continue
+		}
+
+		exchanges, err := rr.externalAPI.GetLatest(ctx, baseCurrencyCode, targetCurrencyCodes)
+		if err != nil {
+			return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		for _, exchange := range exchanges {
+			exchange.Timestamp = rateDate
+			err = rr.store(ctx, exchange)
+			if err != nil {
+				return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Stop прерывает первоначальное заполнение, останавливает планировщик
+// обновления курсов и дожидается завершения уже запущенных задач.
+func (rr *ExchangeRepository) Stop(ctx context.Context) error {
+	op := "internal.Exchange.Stop"
+
+	rr.mu.Lock()
+	scheduler := rr.scheduler
+	rr.scheduler = nil
+	rr.stopped = true
+	cancelSeeding := rr.cancelSeeding
+	seedingDone := rr.seedingDone
+	rr.mu.Unlock()
+
+	if cancelSeeding != nil {
+		cancelSeeding()
+
+		select {
+		case <-seedingDone:
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+	}
+
+	if scheduler == nil {
+		return nil
+	}
+
+	select {
+	case <-scheduler.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+)
+
+const (
+	ExchangeInitPending string = "pending"
+	ExchangeInitRunning string = "running"
+	ExchangeInitReady   string = "ready"
+	// ExchangeInitRetrying - первый проход завершён, но часть дат не
+	// загрузилась и будет загружена повторно.
+	ExchangeInitRetrying string = "retrying"
+	ExchangeInitFailed   string = "failed"
+)
+
+const (
+	seedRetryInitialDelay time.Duration = 30 * time.Second
+	seedRetryMaxDelay     time.Duration = 30 * time.Minute
+)
+
+// ExchangeInitStatus - состояние первоначального заполнения курсов за
+// исторические даты. Пока оно не завершено, запросы обслуживаются по тем
+// данным, которые уже есть в бд.
+type ExchangeInitStatus struct {
+	State          string
+	TotalDates     int
+	CompletedDates int
+	FailedDates    int
+	StartedAt      time.Time
+	FinishedAt     time.Time
+	NextRetryAt    time.Time
+	LastError      string
+}
+
+func (s ExchangeInitStatus) Ready() bool {
+	return s.State == ExchangeInitReady
+}
+
+func (rr *ExchangeRepository) InitStatus() ExchangeInitStatus {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	return rr.initStatus
+}
+
+func (rr *ExchangeRepository) updateInitStatus(update func(status *ExchangeInitStatus)) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	update(&rr.initStatus)
+}
+
+// startSeeding запускает заполнение в фоне. Остановить его можно через Stop.
+func (rr *ExchangeRepository) startSeeding(dates []time.Time) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	rr.mu.Lock()
+	if rr.stopped {
+		rr.mu.Unlock()
+		cancel()
+		return
+	}
+	rr.cancelSeeding = cancel
+	rr.seedingDone = done
+	rr.initStatus = ExchangeInitStatus{
+		State:      ExchangeInitRunning,
+		TotalDates: len(dates),
+		StartedAt:  time.Now(),
+	}
+	rr.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+
+		rr.seedData(ctx, dates)
+	}()
+}
+
+// seedData заполняет курсы за dates. Даты, которые не удалось загрузить,
+// повторяются с растущей паузой, пока не загрузятся все или заполнение не
+// остановят через Stop.
+func (rr *ExchangeRepository) seedData(ctx context.Context, dates []time.Time) {
+	op := "internal.Exchange.seedData"
+
+	pending := dates
+	delay := seedRetryInitialDelay
+	for {
+		pending = rr.seedPass(ctx, pending)
+
+		if ctx.Err() != nil {
+			rr.finishSeeding(ExchangeInitFailed, ctx.Err().Error())
+			return
+		}
+
+		if len(pending) == 0 {
+			rr.finishSeeding(ExchangeInitReady, "")
+			return
+		}
+
+		slog.WarnContext(ctx, "seeding incomplete, retrying failed dates", "op", op, "failed_dates", len(pending), "retry_in", delay)
+		rr.updateInitStatus(func(status *ExchangeInitStatus) {
+			status.State = ExchangeInitRetrying
+			status.NextRetryAt = time.Now().Add(delay)
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			rr.finishSeeding(ExchangeInitFailed, ctx.Err().Error())
+			return
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, seedRetryMaxDelay)
+	}
+}
+
+// seedPass загружает курсы за dates и возвращает даты, которые не удалось
+// загрузить.
+func (rr *ExchangeRepository) seedPass(ctx context.Context, dates []time.Time) []time.Time {
+	op := "internal.Exchange.seedPass"
+
+	var failed []time.Time
+	var lastErr error
+	for i, date := range dates {
+		if ctx.Err() != nil {
+			failed = append(failed, dates[i:]...)
+			break
+		}
+
+		err := rr.seedDate(ctx, date)
+		if err != nil {
+			slog.ErrorContext(ctx, "seeding date failed", "op", op, "date", date.Format(dataFormat), "error", err)
+			failed = append(failed, date)
+			lastErr = err
+			continue
+		}
+
+		rr.updateInitStatus(func(status *ExchangeInitStatus) {
+			status.CompletedDates++
+		})
+	}
+
+	rr.updateInitStatus(func(status *ExchangeInitStatus) {
+		status.FailedDates = len(failed)
+		if lastErr != nil {
+			status.LastError = lastErr.Error()
+		}
+	})
+
+	var seedErr error
+	if lastErr != nil {
+		seedErr = fmt.Errorf("%s: %w", op, lastErr)
+	}
+	metrics.ObserveSchedulerRun("exchange_seed", seedErr)
+
+	return failed
+}
+
+func (rr *ExchangeRepository) finishSeeding(state, lastError string) {
+	rr.updateInitStatus(func(status *ExchangeInitStatus) {
+		status.State = state
+		status.FinishedAt = time.Now()
+		status.NextRetryAt = time.Time{}
+		if lastError != "" {
+			status.LastError = lastError
+		}
+	})
+}
+
+// seedDate запрашивает стороннее апи только если в бд не хватает курсов на дату.
+func (rr *ExchangeRepository) seedDate(ctx context.Context, date time.Time) error {
+	op := "internal.Exchange.seedDate"
+	ctx, span := tracing.Start(ctx, op, tracing.DateKey.String(date.Format(dataFormat)))
+	defer span.End()
+
+	_, missingExchange, err := rr.getByDateFromDb(ctx, date, defaultBase)
+	if err != nil {
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if len(missingExchange) == 0 {
+		return nil
+	}
+
+	exchanges, err := rr.getByDateFromExAPI(ctx, date, missingExchange)
+	if err != nil {
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	err = rr.setByMisToDb(ctx, date, missingExchange, exchanges)
+	if err != nil {
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal/calendar"
+	"github.com/sashaem1/ExchangeRate/internal/clock"
+)
+
+// memoryExchangeStorage хранит курсы по паре и дате.
+type memoryExchangeStorage struct {
+	ExchangeStorage
+	rates map[string]Exchange
+}
+
+func memoryKey(baseCurrencyCode, targetCurrencyCode string, date time.Time) string {
+	return baseCurrencyCode + "/" + targetCurrencyCode + "@" + date.Format(dataFormat)
+}
+
+func (s *memoryExchangeStorage) GetMany(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, date time.Time) ([]Exchange, error) {
+	var exchanges []Exchange
+	for _, code := range targetCurrencyCodes {
+		if exchange, ok := s.rates[memoryKey(baseCurrencyCode, code, date)]; ok {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+	return exchanges, nil
+}
+
+func (s *memoryExchangeStorage) Set(ctx context.Context, exchange Exchange) (bool, error) {
+	s.rates[memoryKey(exchange.BaseCurrency.Code, exchange.TargetCurrency.Code, exchange.Timestamp)] = exchange
+	return true, nil
+}
+
+// fakeExternalAPI отдаёт курс 1 на любую пару и запоминает запросы.
+type fakeExternalAPI struct {
+	latest []string
+	byDate map[string][]string
+}
+
+func (a *fakeExternalAPI) Name() string { return "fake" }
+
+func (a *fakeExternalAPI) GetLatest(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string) ([]Exchange, error) {
+	a.latest = append(a.latest, targetCurrencyCodes...)
+	return a.rates(baseCurrencyCode, targetCurrencyCodes, time.Time{})
+}
+
+func (a *fakeExternalAPI) GetByDate(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, date time.Time) ([]Exchange, error) {
+	key := date.Format(dataFormat)
+	a.byDate[key] = append(a.byDate[key], targetCurrencyCodes...)
+	return a.rates(baseCurrencyCode, targetCurrencyCodes, date)
+}
+
+func (a *fakeExternalAPI) rates(baseCurrencyCode string, targetCurrencyCodes []string, date time.Time) ([]Exchange, error) {
+	exchanges := make([]Exchange, 0, len(targetCurrencyCodes))
+	for _, code := range targetCurrencyCodes {
+		exchange, err := NewExchange(baseCurrencyCode, code, 1, date)
+		if err != nil {
+			return nil, err
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges, nil
+}
+
+// newHolidayRepository возвращает репозиторий, у которого 2024-03-04
+// (понедельник) - праздник японского рынка, а сейчас 2024-03-04 13:00 UTC.
+func newHolidayRepository(t *testing.T) (*ExchangeRepository, *memoryExchangeStorage, *fakeExternalAPI) {
+	t.Helper()
+
+	businessCalendar, err := calendar.New(calendar.Config{Markets: map[string]calendar.MarketConfig{
+		"tokyo": {Currencies: []string{"JPY"}, Holidays: []string{"2024-03-04"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &memoryExchangeStorage{rates: make(map[string]Exchange)}
+	api := &fakeExternalAPI{byDate: make(map[string][]string)}
+	fake := clock.NewFake(time.Date(2024, 3, 4, 13, 0, 0, 0, time.UTC), time.UTC, 0)
+
+	return NewExchangeRepository(storage, nil, api, nil, businessCalendar, fake, nil), storage, api
+}
+
+func TestGetLatestOnHoliday(t *testing.T) {
+	repo, _, api := newHolidayRepository(t)
+
+	exchanges, err := repo.GetLatest(context.Background(), "USD", []string{"EUR", "JPY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exchanges[0].Timestamp.Format(dataFormat); exchanges[0].TargetCurrency.Code != "EUR" || got != "2024-03-04" {
+		t.Errorf("USD/EUR = %s on %s, want 2024-03-04", exchanges[0].Pair(), got)
+	}
+	if got := exchanges[1].Timestamp.Format(dataFormat); exchanges[1].TargetCurrency.Code != "JPY" || got != "2024-03-01" {
+		t.Errorf("USD/JPY = %s on %s, want previous business day 2024-03-01", exchanges[1].Pair(), got)
+	}
+	if !slices.Equal(api.latest, []string{"EUR"}) {
+		t.Errorf("latest rates fetched for %v, want [EUR]", api.latest)
+	}
+	if !slices.Equal(api.byDate["2024-03-01"], []string{"JPY"}) {
+		t.Errorf("rates on 2024-03-01 fetched for %v, want [JPY]", api.byDate["2024-03-01"])
+	}
+}
+
+func TestInitDataSkipsNonBusinessPairs(t *testing.T) {
+	repo, storage, api := newHolidayRepository(t)
+
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	saturday := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	err := repo.initData(context.Background(), []time.Time{saturday, monday})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fetched := api.byDate[saturday.Format(dataFormat)]; len(fetched) != 0 {
+		t.Errorf("rates on Saturday fetched for %v", fetched)
+	}
+	for key := range storage.rates {
+		baseCurrencyCode, targetCurrencyCode := key[:3], key[4:7]
+		if baseCurrencyCode == "JPY" || targetCurrencyCode == "JPY" {
+			t.Errorf("rate %s stored on a non-business day for JPY", key)
+		}
+	}
+	if _, ok := storage.rates[memoryKey("USD", "EUR", monday)]; !ok {
+		t.Errorf("USD/EUR is not stored on %s", monday.Format(dataFormat))
+	}
+}
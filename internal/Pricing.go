@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// basisPoints - базисных пунктов в единице. Спред в 100% и больше делает bid
+// неположительным, поэтому он же служит верхней границей спреда.
+const basisPoints float64 = 10000
+
+// PricingProfile - наценка партнёра, привязанная к его API ключу. Спред
+// задаётся в базисных пунктах как ширина между bid и ask относительно mid:
+// при 20 б.п. bid на 0.1% ниже mid, ask на 0.1% выше.
+//
+// Ключ Spreads - пара "USD/EUR" или имя группы из Groups. Спред пары
+// действует в обе стороны. Если пары нет, берётся наибольший спред среди
+// групп, в которые входит одна из валют пары, а если нет и таких - DefaultSpreadBps.
+type PricingProfile struct {
+	Name             string
+	DefaultSpreadBps float64
+	Groups           map[string][]string
+	Spreads          map[string]float64
+}
+
+// Quote - цены пары для клиента: mid - курс из хранилища, bid и ask -
+// курсы с наценкой профиля.
+type Quote struct {
+	Bid       float64
+	Ask       float64
+	Mid       float64
+	SpreadBps float64
+}
+
+func NewPricingProfile(name string, defaultSpreadBps float64, groups map[string][]string, spreads map[string]float64) (*PricingProfile, error) {
+	op := "internal.Pricing.NewPricingProfile"
+
+	err := checkSpread(defaultSpreadBps)
+	if err != nil {
+		return nil, fmt.Errorf("%s: profile %s: default: %w", op, name, err)
+	}
+
+	profile := &PricingProfile{
+		Name:             name,
+		DefaultSpreadBps: defaultSpreadBps,
+		Groups:           make(map[string][]string, len(groups)),
+		Spreads:          make(map[string]float64, len(spreads)),
+	}
+
+	for group, codes := range groups {
+		if strings.Contains(group, "/") {
+			return nil, fmt.Errorf("%s: profile %s: group name %q must not contain '/'", op, name, group)
+		}
+
+		currencies := make([]string, 0, len(codes))
+		for _, code := range codes {
+			currency, err := NewCurrency(code)
+			if err != nil {
+				return nil, fmt.Errorf("%s: profile %s: group %s: %w", op, name, group, err)
+			}
+			currencies = append(currencies, currency.Code)
+		}
+		profile.Groups[group] = currencies
+	}
+
+	for key, spread := range spreads {
+		err := checkSpread(spread)
+		if err != nil {
+			return nil, fmt.Errorf("%s: profile %s: %s: %w", op, name, key, err)
+		}
+
+		base, target, isPair := strings.Cut(key, "/")
+		if !isPair {
+			if _, ok := profile.Groups[key]; !ok {
+				return nil, fmt.Errorf("%s: profile %s: unknown group %q", op, name, key)
+			}
+			profile.Spreads[key] = spread
+			continue
+		}
+
+		baseCurrency, err := NewCurrency(base)
+		if err != nil {
+			return nil, fmt.Errorf("%s: profile %s: %w", op, name, err)
+		}
+		targetCurrency, err := NewCurrency(target)
+		if err != nil {
+			return nil, fmt.Errorf("%s: profile %s: %w", op, name, err)
+		}
+		profile.Spreads[pricingPair(baseCurrency.Code, targetCurrency.Code)] = spread
+	}
+
+	return profile, nil
+}
+
+// SpreadBps возвращает спред профиля для пары. У ключа без профиля спреда нет.
+func (p *PricingProfile) SpreadBps(base, target string) float64 {
+	if p == nil {
+		return 0
+	}
+
+	base, target = strings.ToUpper(base), strings.ToUpper(target)
+
+	if spread, ok := p.Spreads[pricingPair(base, target)]; ok {
+		return spread
+	}
+	if spread, ok := p.Spreads[pricingPair(target, base)]; ok {
+		return spread
+	}
+
+	spread, found := 0.0, false
+	for group, currencies := range p.Groups {
+		groupSpread, ok := p.Spreads[group]
+		if !ok || !containsCurrency(currencies, base, target) {
+			continue
+		}
+		if !found || groupSpread > spread {
+			spread, found = groupSpread, true
+		}
+	}
+	if found {
+		return spread
+	}
+
+	return p.DefaultSpreadBps
+}
+
+// Quote считает bid и ask пары от курса mid.
+func (p *PricingProfile) Quote(base, target string, mid float64) Quote {
+	spread := p.SpreadBps(base, target)
+	half := mid * spread / basisPoints / 2
+
+	return Quote{
+		Bid:       mid - half,
+		Ask:       mid + half,
+		Mid:       mid,
+		SpreadBps: spread,
+	}
+}
+
+// PricedConversion - пересчёт суммы с наценкой профиля. Result считается по
+// mid, BidResult - по bid (сумма, которую клиент получит, продавая From),
+// AskResult - по ask (сумма, которую клиент заплатит, покупая From).
+type PricedConversion struct {
+	Conversion
+	Quote     Quote
+	BidResult float64
+	AskResult float64
+}
+
+// Convert пересчитывает сумму conversion по bid и ask пары.
+func (p *PricingProfile) Convert(conversion Conversion) PricedConversion {
+	quote := p.Quote(conversion.From.Code, conversion.To.Code, conversion.Rate)
+
+	return PricedConversion{
+		Conversion: conversion,
+		Quote:      quote,
+		BidResult:  conversion.Amount * quote.Bid,
+		AskResult:  conversion.Amount * quote.Ask,
+	}
+}
+
+func checkSpread(spread float64) error {
+	if spread < 0 || spread >= basisPoints {
+		return fmt.Errorf("spread %v bps is out of range [0, %v)", spread, basisPoints)
+	}
+
+	return nil
+}
+
+func pricingPair(base, target string) string {
+	return base + "/" + target
+}
+
+func containsCurrency(currencies []string, codes ...string) bool {
+	for _, currency := range currencies {
+		for _, code := range codes {
+			if currency == code {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func testPricingProfile(t *testing.T) *PricingProfile {
+	t.Helper()
+
+	profile, err := NewPricingProfile("partner", 10,
+		map[string][]string{"majors": {"usd"}, "exotic": {"RUB"}},
+		map[string]float64{"eur/rub": 50, "majors": 20, "exotic": 80},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return profile
+}
+
+func TestPricingProfileSpreadBps(t *testing.T) {
+	profile := testPricingProfile(t)
+
+	tests := []struct {
+		name   string
+		base   string
+		target string
+		want   float64
+	}{
+		{"pair", "EUR", "RUB", 50},
+		{"reverse pair", "RUB", "EUR", 50},
+		{"pair in lower case", "eur", "rub", 50},
+		{"group", "USD", "JPY", 20},
+		{"largest group", "USD", "RUB", 80},
+		{"default", "EUR", "JPY", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := profile.SpreadBps(tt.base, tt.target); got != tt.want {
+				t.Errorf("SpreadBps(%s, %s) = %v, want %v", tt.base, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricingProfileQuote(t *testing.T) {
+	profile := testPricingProfile(t)
+
+	quote := profile.Quote("EUR", "RUB", 100)
+	if quote.Mid != 100 || quote.SpreadBps != 50 {
+		t.Errorf("Quote() = %+v", quote)
+	}
+	if math.Abs(quote.Bid-99.75) > 1e-9 || math.Abs(quote.Ask-100.25) > 1e-9 {
+		t.Errorf("Quote() bid = %v, ask = %v, want 99.75, 100.25", quote.Bid, quote.Ask)
+	}
+
+	var none *PricingProfile
+	if quote := none.Quote("USD", "RUB", 100); quote.Bid != 100 || quote.Ask != 100 || quote.SpreadBps != 0 {
+		t.Errorf("Quote() without profile = %+v, want no spread", quote)
+	}
+}
+
+func TestPricingProfileConvert(t *testing.T) {
+	profile := testPricingProfile(t)
+
+	conversion := Conversion{From: Currency{Code: "USD"}, To: Currency{Code: "EUR"}, Amount: 10, Rate: 0.9, Result: 9}
+	priced := profile.Convert(conversion)
+	if priced.Result != 9 {
+		t.Errorf("Result = %v, want 9", priced.Result)
+	}
+	if math.Abs(priced.BidResult-8.991) > 1e-9 || math.Abs(priced.AskResult-9.009) > 1e-9 {
+		t.Errorf("BidResult = %v, AskResult = %v, want 8.991, 9.009", priced.BidResult, priced.AskResult)
+	}
+}
+
+func TestNewPricingProfileRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults float64
+		groups   map[string][]string
+		spreads  map[string]float64
+	}{
+		{"negative default", -1, nil, nil},
+		{"default of 100%", basisPoints, nil, nil},
+		{"unknown currency in group", 0, map[string][]string{"majors": {"XXX"}}, nil},
+		{"group name with slash", 0, map[string][]string{"a/b": {"USD"}}, nil},
+		{"unknown group", 0, nil, map[string]float64{"majors": 10}},
+		{"unknown currency in pair", 0, nil, map[string]float64{"USD/XXX": 10}},
+		{"spread out of range", 0, nil, map[string]float64{"USD/EUR": basisPoints}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPricingProfile("partner", tt.defaults, tt.groups, tt.spreads)
+			if err == nil {
+				t.Errorf("NewPricingProfile() error = nil, want error")
+			}
+		})
+	}
+}
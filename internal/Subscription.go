@@ -0,0 +1,630 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal/broker"
+)
+
+type SubscriptionID string
+
+type SubscriptionConditionType string
+
+const (
+	// SubscriptionOnUpdate срабатывает на каждый новый или изменившийся курс.
+	SubscriptionOnUpdate SubscriptionConditionType = "update"
+	// SubscriptionOnThreshold срабатывает, когда курс пересекает Threshold
+	// относительно предыдущего сохранённого курса пары.
+	SubscriptionOnThreshold SubscriptionConditionType = "threshold"
+	// SubscriptionOnChange срабатывает, когда курс изменился относительно
+	// предыдущего сохранённого курса не меньше чем на Percent процентов.
+	SubscriptionOnChange SubscriptionConditionType = "change"
+)
+
+type SubscriptionCondition struct {
+	Type      SubscriptionConditionType
+	Threshold float64
+	Percent   float64
+}
+
+// Subscription - вебхук, на который отправляются события по парам Pairs.
+// Secret используется для подписи тела запроса и показывается клиенту только
+// при создании.
+type Subscription struct {
+	ID         SubscriptionID
+	APIKey     string
+	URL        string
+	Secret     string
+	Pairs      []string
+	Conditions []SubscriptionCondition
+	CreatedAt  time.Time
+}
+
+type DeliveryID string
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery - одна отправка события на вебхук вместе с историей попыток.
+// EventKey уникален в пределах подписки и защищает от повторной отправки
+// одного и того же события.
+type Delivery struct {
+	ID             DeliveryID
+	SubscriptionID SubscriptionID
+	EventKey       string
+	Payload        []byte
+	Status         DeliveryStatus
+	Attempts       int
+	ResponseStatus int
+	LastError      string
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	DeliveredAt    time.Time
+}
+
+// SubscriptionEvent - тело запроса на вебхук.
+type SubscriptionEvent struct {
+	SubscriptionID SubscriptionID            `json:"subscription_id"`
+	Event          SubscriptionConditionType `json:"event"`
+	Pair           string                    `json:"pair"`
+	Base           string                    `json:"base"`
+	Target         string                    `json:"target"`
+	Rate           float64                   `json:"rate"`
+	PreviousRate   float64                   `json:"previous_rate,omitempty"`
+	ChangePercent  float64                   `json:"change_percent,omitempty"`
+	Threshold      float64                   `json:"threshold,omitempty"`
+	Date           string                    `json:"date"`
+}
+
+const (
+	subscriptionSecretBytes  int           = 32
+	subscriptionMaxPairs     int           = 50
+	deliveryMaxAttempts      int           = 6
+	deliveryRetryBase        time.Duration = 30 * time.Second
+	deliveryRetryMax         time.Duration = time.Hour
+	deliveryPollInterval     time.Duration = time.Second
+	deliveryBatchSize        int           = 50
+	deliveryTimeout          time.Duration = 10 * time.Second
+	deliveryDefaultListLimit int           = 100
+)
+
+func NewSubscription(apiKey, webhookURL string, pairs []string, conditions []SubscriptionCondition) (Subscription, error) {
+	op := "internal.Subscription.NewSubscription"
+
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Hostname() == "" || !webhookHostAllowed(parsedURL.Hostname()) {
+		err := ErrInvalidWebhookURL.With(map[string]any{"url": webhookURL})
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(pairs) == 0 {
+		err := ErrMissingParameter.With(map[string]any{"name": "pairs"})
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(pairs) > subscriptionMaxPairs {
+		err := ErrInvalidArgument.With(map[string]any{"pairs": len(pairs), "max_pairs": subscriptionMaxPairs})
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pairs, err = ParsePairs(pairs)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(conditions) == 0 {
+		conditions = []SubscriptionCondition{{Type: SubscriptionOnUpdate}}
+	}
+	for _, condition := range conditions {
+		if err := validateSubscriptionCondition(condition); err != nil {
+			return Subscription{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	secret := make([]byte, subscriptionSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	subscription := Subscription{
+		APIKey:     apiKey,
+		URL:        parsedURL.String(),
+		Secret:     hex.EncodeToString(secret),
+		Pairs:      pairs,
+		Conditions: conditions,
+		CreatedAt:  time.Now(),
+	}
+
+	return subscription, nil
+}
+
+// WebhookAddressAllowed сообщает, можно ли отправлять вебхук на адрес ip.
+// Loopback, частные, link-local, multicast и неуказанные адреса запрещены,
+// чтобы через вебхук нельзя было обратиться к внутренней сети сервиса.
+func WebhookAddressAllowed(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// webhookHostAllowed отсекает запрещённые адреса, записанные в самом URL.
+// Имена хостов проверяются после разрешения в CreateSubscription и заново при
+// каждом соединении в отправителе вебхуков.
+func webhookHostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return true
+	}
+
+	return WebhookAddressAllowed(ip)
+}
+
+func validateSubscriptionCondition(condition SubscriptionCondition) error {
+	switch condition.Type {
+	case SubscriptionOnUpdate:
+		return nil
+	case SubscriptionOnThreshold:
+		if condition.Threshold > 0 {
+			return nil
+		}
+	case SubscriptionOnChange:
+		if condition.Percent > 0 {
+			return nil
+		}
+	}
+
+	return ErrInvalidArgument.With(map[string]any{"condition": condition.Type})
+}
+
+// match проверяет условие для нового курса. previous - предыдущий
+// сохранённый курс той же пары, ноль, если его нет в бд.
+func (c SubscriptionCondition) match(exchange Exchange, previous float64) (SubscriptionEvent, bool) {
+	event := SubscriptionEvent{
+		Event:        c.Type,
+		Pair:         exchange.Pair(),
+		Base:         exchange.BaseCurrency.Code,
+		Target:       exchange.TargetCurrency.Code,
+		Rate:         exchange.Rate,
+		PreviousRate: previous,
+		Date:         exchange.Timestamp.Format(dataFormat),
+	}
+	if previous > 0 {
+		event.ChangePercent = (exchange.Rate - previous) / previous * 100
+	}
+
+	switch c.Type {
+	case SubscriptionOnUpdate:
+		return event, true
+	case SubscriptionOnThreshold:
+		if previous <= 0 {
+			return event, false
+		}
+		event.Threshold = c.Threshold
+		crossedUp := previous < c.Threshold && exchange.Rate >= c.Threshold
+		crossedDown := previous > c.Threshold && exchange.Rate <= c.Threshold
+		return event, crossedUp || crossedDown
+	case SubscriptionOnChange:
+		return event, previous > 0 && math.Abs(event.ChangePercent) >= c.Percent
+	}
+
+	return event, false
+}
+
+func (c SubscriptionCondition) eventKey(exchange Exchange) string {
+	key := string(c.Type) + ":" + exchange.Pair() + ":" + exchange.Timestamp.Format(dataFormat)
+
+	switch c.Type {
+	case SubscriptionOnUpdate:
+		key += ":" + strconv.FormatFloat(exchange.Rate, 'g', -1, 64)
+	case SubscriptionOnThreshold:
+		key += ":" + strconv.FormatFloat(c.Threshold, 'g', -1, 64)
+	case SubscriptionOnChange:
+		key += ":" + strconv.FormatFloat(c.Percent, 'g', -1, 64)
+	}
+
+	return key
+}
+
+// SubscriptionStorage ищет подписки в пределах apiKey; пустой apiKey
+// отключает проверку владельца. Отсутствующая подписка - ErrSubscriptionNotFound.
+type SubscriptionStorage interface {
+	Create(ctx context.Context, subscription Subscription) (Subscription, error)
+	Get(ctx context.Context, apiKey string, id SubscriptionID) (Subscription, error)
+	List(ctx context.Context, apiKey string) ([]Subscription, error)
+	ListByPair(ctx context.Context, pair string) ([]Subscription, error)
+	Delete(ctx context.Context, apiKey string, id SubscriptionID) error
+	// EnqueueDelivery возвращает false, если событие с таким EventKey уже
+	// ставилось в очередь для подписки.
+	EnqueueDelivery(ctx context.Context, delivery Delivery) (bool, error)
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery Delivery) error
+	ListDeliveries(ctx context.Context, id SubscriptionID, limit int) ([]Delivery, error)
+}
+
+// WebhookSender отправляет подписанное тело запроса и возвращает HTTP
+// статус ответа.
+type WebhookSender interface {
+	Send(ctx context.Context, webhookURL, secret string, delivery Delivery) (int, error)
+}
+
+// SubscriptionRepository управляет вебхуками: получает из брокера новые
+// курсы, проверяет условия подписок, ставит события в очередь доставки в бд и
+// отправляет их с повторными попытками.
+type SubscriptionRepository struct {
+	storage         SubscriptionStorage
+	exchangeStorage ExchangeStorage
+	sender          WebhookSender
+	lookupHost      func(ctx context.Context, network, host string) ([]netip.Addr, error)
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    sync.WaitGroup
+	running bool
+}
+
+func NewSubscriptionRepository(storage SubscriptionStorage, exchangeStorage ExchangeStorage, sender WebhookSender) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		storage:         storage,
+		exchangeStorage: exchangeStorage,
+		sender:          sender,
+		lookupHost:      net.DefaultResolver.LookupNetIP,
+	}
+}
+
+func (rr *SubscriptionRepository) CreateSubscription(ctx context.Context, apiKey, webhookURL string, pairs []string, conditions []SubscriptionCondition) (Subscription, error) {
+	op := "internal.Subscription.CreateSubscription"
+
+	subscription, err := NewSubscription(apiKey, webhookURL, pairs, conditions)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = rr.checkWebhookHost(ctx, subscription.URL)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	subscription, err = rr.storage.Create(ctx, subscription)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subscription, nil
+}
+
+// checkWebhookHost разрешает имя хоста вебхука и отклоняет его, если хотя
+// бы один из адресов запрещён.
+func (rr *SubscriptionRepository) checkWebhookHost(ctx context.Context, webhookURL string) error {
+	op := "internal.Subscription.checkWebhookHost"
+
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	invalid := ErrInvalidWebhookURL.With(map[string]any{"url": webhookURL})
+
+	addrs, err := rr.lookupHost(ctx, "ip", parsedURL.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%s: %w", op, invalid)
+	}
+
+	for _, addr := range addrs {
+		if !WebhookAddressAllowed(addr) {
+			return fmt.Errorf("%s: %w", op, invalid)
+		}
+	}
+
+	return nil
+}
+
+func (rr *SubscriptionRepository) GetSubscription(ctx context.Context, apiKey string, id SubscriptionID) (Subscription, error) {
+	op := "internal.Subscription.GetSubscription"
+
+	subscription, err := rr.storage.Get(ctx, apiKey, id)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subscription, nil
+}
+
+func (rr *SubscriptionRepository) ListSubscriptions(ctx context.Context, apiKey string) ([]Subscription, error) {
+	op := "internal.Subscription.ListSubscriptions"
+
+	subscriptions, err := rr.storage.List(ctx, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subscriptions, nil
+}
+
+func (rr *SubscriptionRepository) DeleteSubscription(ctx context.Context, apiKey string, id SubscriptionID) error {
+	op := "internal.Subscription.DeleteSubscription"
+
+	err := rr.storage.Delete(ctx, apiKey, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries возвращает журнал доставок подписки, новые первыми.
+func (rr *SubscriptionRepository) ListDeliveries(ctx context.Context, apiKey string, id SubscriptionID) ([]Delivery, error) {
+	op := "internal.Subscription.ListDeliveries"
+
+	_, err := rr.storage.Get(ctx, apiKey, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries, err := rr.storage.ListDeliveries(ctx, id, deliveryDefaultListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// Notify проверяет условия всех подписок на пару курса и ставит сработавшие
+// события в очередь доставки.
+func (rr *SubscriptionRepository) Notify(ctx context.Context, exchange Exchange) error {
+	op := "internal.Subscription.Notify"
+
+	subscriptions, err := rr.storage.ListByPair(ctx, exchange.Pair())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	// Курсы за выходные и праздники не хранятся, поэтому сравнение идёт с
+	// последним сохранённым курсом, а не с календарным вчера.
+	previous, err := rr.exchangeStorage.GetPrevious(ctx, exchange.BaseCurrency.Code, exchange.TargetCurrency.Code, exchange.Timestamp)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, subscription := range subscriptions {
+		for _, condition := range subscription.Conditions {
+			event, ok := condition.match(exchange, previous.Rate)
+			if !ok {
+				continue
+			}
+			event.SubscriptionID = subscription.ID
+
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+			now := time.Now()
+			_, err = rr.storage.EnqueueDelivery(ctx, Delivery{
+				SubscriptionID: subscription.ID,
+				EventKey:       condition.eventKey(exchange),
+				Payload:        payload,
+				Status:         DeliveryPending,
+				NextAttemptAt:  now,
+				CreatedAt:      now,
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Start подписывается на все обновления курсов в rateBroker и запускает
+// отправку вебхуков в фоне.
+func (rr *SubscriptionRepository) Start(rateBroker *broker.Broker[Exchange]) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if rr.running {
+		return
+	}
+	rr.running = true
+	rr.stop = make(chan struct{})
+
+	rr.done.Add(2)
+	go rr.consume(rateBroker)
+	go rr.deliver()
+}
+
+// Stop останавливает фоновые задачи. Доставки, которые не успели уйти,
+// остаются в бд и будут отправлены после перезапуска.
+func (rr *SubscriptionRepository) Stop(ctx context.Context) error {
+	op := "internal.Subscription.Stop"
+
+	rr.mu.Lock()
+	if rr.running {
+		rr.running = false
+		close(rr.stop)
+	}
+	rr.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		rr.done.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
+
+func (rr *SubscriptionRepository) consume(rateBroker *broker.Broker[Exchange]) {
+	op := "internal.Subscription.consume"
+	defer rr.done.Done()
+
+	for {
+		sub, err := rateBroker.Subscribe(nil)
+		if err != nil {
+			slog.Info("subscription consumer stopped", "op", op, "reason", err)
+			return
+		}
+
+	receive:
+		for {
+			select {
+			case <-rr.stop:
+				sub.Unsubscribe()
+				return
+			case exchange, ok := <-sub.C():
+				if !ok {
+					break receive
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+				err := rr.Notify(ctx, exchange)
+				cancel()
+				if err != nil {
+					slog.Error("evaluating subscriptions failed", "op", op, "pair", exchange.Pair(), "error", err)
+				}
+			}
+		}
+
+		// Если обработка отстала, брокер закрывает подписку; курсы, пришедшие
+		// за это время, пропускаются, и подписка оформляется заново.
+		if !errors.Is(sub.Err(), broker.ErrSlowConsumer) {
+			return
+		}
+		slog.Warn("subscription consumer fell behind, resubscribing", "op", op)
+	}
+}
+
+func (rr *SubscriptionRepository) deliver() {
+	defer rr.done.Done()
+
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rr.stop:
+			return
+		case <-ticker.C:
+			rr.deliverDue()
+		}
+	}
+}
+
+func (rr *SubscriptionRepository) deliverDue() {
+	op := "internal.Subscription.deliverDue"
+	ctx := context.Background()
+
+	deliveries, err := rr.storage.DueDeliveries(ctx, time.Now(), deliveryBatchSize)
+	if err != nil {
+		slog.Error("loading due webhook deliveries failed", "op", op, "error", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		select {
+		case <-rr.stop:
+			return
+		default:
+		}
+
+		if err := rr.attempt(ctx, delivery); err != nil {
+			slog.Error("updating webhook delivery failed", "op", op, "delivery", delivery.ID, "error", err)
+		}
+	}
+}
+
+// attempt отправляет событие один раз и записывает результат. После
+// неудачи следующая попытка откладывается экспоненциально, после
+// deliveryMaxAttempts доставка считается проваленной.
+func (rr *SubscriptionRepository) attempt(ctx context.Context, delivery Delivery) error {
+	op := "internal.Subscription.attempt"
+
+	subscription, err := rr.storage.Get(ctx, "", delivery.SubscriptionID)
+	if err != nil {
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			delivery.Status = DeliveryFailed
+			delivery.LastError = "subscription deleted"
+			return rr.updateDelivery(ctx, op, delivery)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	status, err := rr.sender.Send(sendCtx, subscription.URL, subscription.Secret, delivery)
+	cancel()
+
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	if err == nil {
+		delivery.Status = DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = time.Now()
+		return rr.updateDelivery(ctx, op, delivery)
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= deliveryMaxAttempts {
+		delivery.Status = DeliveryFailed
+		slog.Warn("webhook delivery failed", "op", op, "delivery", delivery.ID, "subscription", subscription.ID, "attempts", delivery.Attempts, "error", err)
+	} else {
+		delivery.NextAttemptAt = time.Now().Add(deliveryBackoff(delivery.Attempts))
+	}
+
+	return rr.updateDelivery(ctx, op, delivery)
+}
+
+func (rr *SubscriptionRepository) updateDelivery(ctx context.Context, op string, delivery Delivery) error {
+	err := rr.storage.UpdateDelivery(ctx, delivery)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func deliveryBackoff(attempts int) time.Duration {
+	backoff := deliveryRetryBase << (attempts - 1)
+	if backoff <= 0 || backoff > deliveryRetryMax {
+		return deliveryRetryMax
+	}
+
+	return backoff
+}
@@ -0,0 +1,299 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+type fakeSubscriptionStorage struct {
+	subscriptions map[SubscriptionID]Subscription
+	enqueued      []Delivery
+	updated       []Delivery
+}
+
+func (s *fakeSubscriptionStorage) Create(ctx context.Context, subscription Subscription) (Subscription, error) {
+	s.subscriptions[subscription.ID] = subscription
+	return subscription, nil
+}
+
+func (s *fakeSubscriptionStorage) Get(ctx context.Context, apiKey string, id SubscriptionID) (Subscription, error) {
+	subscription, ok := s.subscriptions[id]
+	if !ok {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+func (s *fakeSubscriptionStorage) List(ctx context.Context, apiKey string) ([]Subscription, error) {
+	return nil, nil
+}
+
+func (s *fakeSubscriptionStorage) ListByPair(ctx context.Context, pair string) ([]Subscription, error) {
+	var subscriptions []Subscription
+	for _, subscription := range s.subscriptions {
+		for _, subscriptionPair := range subscription.Pairs {
+			if subscriptionPair == pair {
+				subscriptions = append(subscriptions, subscription)
+			}
+		}
+	}
+	return subscriptions, nil
+}
+
+func (s *fakeSubscriptionStorage) Delete(ctx context.Context, apiKey string, id SubscriptionID) error {
+	delete(s.subscriptions, id)
+	return nil
+}
+
+func (s *fakeSubscriptionStorage) EnqueueDelivery(ctx context.Context, delivery Delivery) (bool, error) {
+	s.enqueued = append(s.enqueued, delivery)
+	return true, nil
+}
+
+func (s *fakeSubscriptionStorage) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error) {
+	return nil, nil
+}
+
+func (s *fakeSubscriptionStorage) UpdateDelivery(ctx context.Context, delivery Delivery) error {
+	s.updated = append(s.updated, delivery)
+	return nil
+}
+
+func (s *fakeSubscriptionStorage) ListDeliveries(ctx context.Context, id SubscriptionID, limit int) ([]Delivery, error) {
+	return nil, nil
+}
+
+type fakeWebhookSender struct {
+	status int
+	err    error
+	sent   int
+}
+
+func (s *fakeWebhookSender) Send(ctx context.Context, webhookURL, secret string, delivery Delivery) (int, error) {
+	s.sent++
+	return s.status, s.err
+}
+
+// fakeExchangeStorage отдаёт на GetPrevious заданный курс, остальные методы
+// в тестах подписок не используются.
+type fakeExchangeStorage struct {
+	ExchangeStorage
+	previous Exchange
+}
+
+func (s *fakeExchangeStorage) GetPrevious(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, date time.Time) (Exchange, error) {
+	return s.previous, nil
+}
+
+func testExchange(t *testing.T, rate float64, date string) Exchange {
+	t.Helper()
+
+	timestamp, err := time.Parse(dataFormat, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange, err := NewExchange("USD", "EUR", rate, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exchange
+}
+
+func TestSubscriptionConditionMatch(t *testing.T) {
+	threshold := SubscriptionCondition{Type: SubscriptionOnThreshold, Threshold: 1}
+	change := SubscriptionCondition{Type: SubscriptionOnChange, Percent: 2}
+
+	tests := []struct {
+		name      string
+		condition SubscriptionCondition
+		previous  float64
+		rate      float64
+		want      bool
+	}{
+		{"update", SubscriptionCondition{Type: SubscriptionOnUpdate}, 0, 0.9, true},
+		{"threshold crossed up", threshold, 0.99, 1.01, true},
+		{"threshold reached from below", threshold, 0.99, 1, true},
+		{"threshold crossed down", threshold, 1.01, 0.99, true},
+		{"threshold reached from above", threshold, 1.01, 1, true},
+		{"threshold not crossed above", threshold, 1.01, 1.02, false},
+		{"threshold not crossed below", threshold, 0.98, 0.99, false},
+		{"threshold left from exact value", threshold, 1, 1.01, false},
+		{"threshold without previous", threshold, 0, 1.01, false},
+		{"change up", change, 1, 1.03, true},
+		{"change down", change, 1, 0.97, true},
+		{"change exactly percent", change, 1, 1.02, true},
+		{"change below percent", change, 1, 1.01, false},
+		{"change without previous", change, 0, 1.03, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, got := tt.condition.match(testExchange(t, tt.rate, "2024-03-04"), tt.previous)
+			if got != tt.want {
+				t.Errorf("match() = %v, want %v (event %+v)", got, tt.want, event)
+			}
+		})
+	}
+}
+
+func TestSubscriptionConditionMatchEvent(t *testing.T) {
+	condition := SubscriptionCondition{Type: SubscriptionOnChange, Percent: 2}
+
+	event, ok := condition.match(testExchange(t, 1.05, "2024-03-04"), 1)
+	if !ok {
+		t.Fatalf("match() = false, want true")
+	}
+	if event.Pair != "USD/EUR" || event.Date != "2024-03-04" || event.PreviousRate != 1 {
+		t.Errorf("event = %+v", event)
+	}
+	if event.ChangePercent < 4.999 || event.ChangePercent > 5.001 {
+		t.Errorf("ChangePercent = %v, want 5", event.ChangePercent)
+	}
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, deliveryRetryBase},
+		{2, 2 * deliveryRetryBase},
+		{3, 4 * deliveryRetryBase},
+		{7, 32 * time.Minute},
+		{8, deliveryRetryMax},
+		{64, deliveryRetryMax},
+		{1000, deliveryRetryMax},
+	}
+	for _, tt := range tests {
+		if got := deliveryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("deliveryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestAttemptFailsAfterMaxAttempts(t *testing.T) {
+	storage := &fakeSubscriptionStorage{subscriptions: map[SubscriptionID]Subscription{
+		"sub-1": {ID: "sub-1", URL: "https://example.com/hook", Secret: "secret"},
+	}}
+	sender := &fakeWebhookSender{status: 500, err: errors.New("unexpected status 500")}
+	repo := NewSubscriptionRepository(storage, nil, sender)
+
+	delivery := Delivery{ID: "delivery-1", SubscriptionID: "sub-1", Status: DeliveryPending}
+	for attempt := 1; attempt <= deliveryMaxAttempts; attempt++ {
+		err := repo.attempt(context.Background(), delivery)
+		if err != nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+		delivery = storage.updated[len(storage.updated)-1]
+
+		if delivery.Attempts != attempt {
+			t.Fatalf("attempt %d: Attempts = %d", attempt, delivery.Attempts)
+		}
+		if delivery.ResponseStatus != 500 || delivery.LastError == "" {
+			t.Errorf("attempt %d: ResponseStatus = %d, LastError = %q", attempt, delivery.ResponseStatus, delivery.LastError)
+		}
+
+		wantStatus := DeliveryPending
+		if attempt == deliveryMaxAttempts {
+			wantStatus = DeliveryFailed
+		}
+		if delivery.Status != wantStatus {
+			t.Fatalf("attempt %d: Status = %s, want %s", attempt, delivery.Status, wantStatus)
+		}
+	}
+
+	if sender.sent != deliveryMaxAttempts {
+		t.Errorf("sent %d times, want %d", sender.sent, deliveryMaxAttempts)
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	t.Run("delivered", func(t *testing.T) {
+		storage := &fakeSubscriptionStorage{subscriptions: map[SubscriptionID]Subscription{
+			"sub-1": {ID: "sub-1", URL: "https://example.com/hook"},
+		}}
+		repo := NewSubscriptionRepository(storage, nil, &fakeWebhookSender{status: 200})
+
+		err := repo.attempt(context.Background(), Delivery{ID: "delivery-1", SubscriptionID: "sub-1", Attempts: 2, LastError: "timeout"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		delivery := storage.updated[0]
+		if delivery.Status != DeliveryDelivered || delivery.Attempts != 3 || delivery.LastError != "" || delivery.DeliveredAt.IsZero() {
+			t.Errorf("delivery = %+v", delivery)
+		}
+	})
+
+	t.Run("subscription deleted", func(t *testing.T) {
+		storage := &fakeSubscriptionStorage{subscriptions: map[SubscriptionID]Subscription{}}
+		sender := &fakeWebhookSender{status: 200}
+		repo := NewSubscriptionRepository(storage, nil, sender)
+
+		err := repo.attempt(context.Background(), Delivery{ID: "delivery-1", SubscriptionID: "sub-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if storage.updated[0].Status != DeliveryFailed {
+			t.Errorf("Status = %s, want %s", storage.updated[0].Status, DeliveryFailed)
+		}
+		if sender.sent != 0 {
+			t.Errorf("sent %d times, want 0", sender.sent)
+		}
+	})
+}
+
+func TestNotifyComparesWithPreviousStoredRate(t *testing.T) {
+	storage := &fakeSubscriptionStorage{subscriptions: map[SubscriptionID]Subscription{
+		"sub-1": {
+			ID:         "sub-1",
+			Pairs:      []string{"USD/EUR"},
+			Conditions: []SubscriptionCondition{{Type: SubscriptionOnThreshold, Threshold: 1}},
+		},
+	}}
+	// Курс пятницы - последний сохранённый перед понедельником
+	exchanges := &fakeExchangeStorage{previous: testExchange(t, 0.99, "2024-03-01")}
+	repo := NewSubscriptionRepository(storage, exchanges, &fakeWebhookSender{})
+
+	err := repo.Notify(context.Background(), testExchange(t, 1.01, "2024-03-04"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(storage.enqueued) != 1 {
+		t.Fatalf("enqueued %d deliveries, want 1", len(storage.enqueued))
+	}
+	if delivery := storage.enqueued[0]; delivery.SubscriptionID != "sub-1" || delivery.Status != DeliveryPending {
+		t.Errorf("delivery = %+v", delivery)
+	}
+}
+
+func TestWebhookAddressAllowed(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := WebhookAddressAllowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("WebhookAddressAllowed(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary - описательная статистика ряда курсов.
+type Summary struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	Median float64
+	// StdDev - выборочное стандартное отклонение (делитель n-1), для ряда из
+	// одного значения равно нулю.
+	StdDev float64
+	First  float64
+	Last   float64
+	// ChangePercent - изменение последнего значения относительно первого в
+	// процентах.
+	ChangePercent float64
+}
+
+// Summarize считает статистику по значениям в хронологическом порядке:
+// First и Last берутся из начала и конца values. Для пустого ряда
+// возвращается нулевая Summary.
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+
+	summary := Summary{
+		Count: len(values),
+		Min:   values[0],
+		Max:   values[0],
+		First: values[0],
+		Last:  values[len(values)-1],
+	}
+
+	var sum float64
+	for _, value := range values {
+		summary.Min = math.Min(summary.Min, value)
+		summary.Max = math.Max(summary.Max, value)
+		sum += value
+	}
+	summary.Mean = sum / float64(len(values))
+
+	if len(values) > 1 {
+		var squares float64
+		for _, value := range values {
+			squares += (value - summary.Mean) * (value - summary.Mean)
+		}
+		summary.StdDev = math.Sqrt(squares / float64(len(values)-1))
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		summary.Median = (sorted[middle-1] + sorted[middle]) / 2
+	} else {
+		summary.Median = sorted[middle]
+	}
+
+	if summary.First != 0 {
+		summary.ChangePercent = (summary.Last - summary.First) / summary.First * 100
+	}
+
+	return summary
+}
@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/i18n"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const errorDomain string = "exchangerate"
+
+var errorCodes = map[internal.ErrorCode]codes.Code{
+	internal.CodeInvalidArgument:      codes.InvalidArgument,
+	internal.CodeMissingParameter:     codes.InvalidArgument,
+	internal.CodeInvalidCurrencyCode:  codes.InvalidArgument,
+	internal.CodeSameCurrency:         codes.InvalidArgument,
+	internal.CodeInvalidRate:          codes.InvalidArgument,
+	internal.CodeInvalidAmount:        codes.InvalidArgument,
+	internal.CodeInvalidActionType:    codes.InvalidArgument,
+	internal.CodeUnknownActionType:    codes.InvalidArgument,
+	internal.CodeInvalidTimeRange:     codes.InvalidArgument,
+	internal.CodeRangeTooLarge:        codes.InvalidArgument,
+	internal.CodeUnsupportedFormat:    codes.InvalidArgument,
+	internal.CodeInvalidDate:          codes.InvalidArgument,
+	internal.CodeUnsupportedCurrency:  codes.InvalidArgument,
+	internal.CodeDateInFuture:         codes.OutOfRange,
+	internal.CodeDateTooEarly:         codes.OutOfRange,
+	internal.CodeUnauthorized:         codes.Unauthenticated,
+	internal.CodeNotFound:             codes.NotFound,
+	internal.CodeSubscriptionNotFound: codes.NotFound,
+	internal.CodeNotBusinessDay:       codes.NotFound,
+	internal.CodeInvalidWebhookURL:    codes.InvalidArgument,
+	internal.CodeUpstreamError:        codes.Internal,
+	internal.CodeUpstreamUnavailable:  codes.Unavailable,
+}
+
+// toStatus переводит ошибку в статус gRPC так же, как writeError в http:
+// код предметной области передаётся в ErrorInfo.Reason, сообщение
+// переводится на язык из метаданных accept-language, а внутренние ошибки
+// только логируются.
+func toStatus(ctx context.Context, op string, err error) error {
+	lang := i18n.Negotiate(firstMetadata(ctx, "lang"), firstMetadata(ctx, "accept-language"))
+
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	var domainErr *internal.Error
+	if !errors.As(err, &domainErr) {
+		slog.ErrorContext(ctx, "request failed", "op", op, "error", err)
+		return status.Error(codes.Internal, i18n.Message(lang, string(internal.CodeInternal), nil))
+	}
+
+	code, ok := errorCodes[domainErr.Code]
+	if !ok {
+		code = codes.Internal
+	}
+
+	if isServerError(code) {
+		slog.ErrorContext(ctx, "request failed", "op", op, "code", domainErr.Code, "error", err)
+	} else {
+		slog.InfoContext(ctx, "request rejected", "op", op, "code", domainErr.Code, "error", err)
+	}
+
+	st := status.New(code, i18n.Message(lang, string(domainErr.Code), domainErr.Details))
+
+	info := &errdetails.ErrorInfo{
+		Reason:   string(domainErr.Code),
+		Domain:   errorDomain,
+		Metadata: make(map[string]string, len(domainErr.Details)),
+	}
+	for key, value := range domainErr.Details {
+		info.Metadata[key] = fmt.Sprint(value)
+	}
+
+	withDetails, detailsErr := st.WithDetails(info)
+	if detailsErr != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
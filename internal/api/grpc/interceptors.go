@@ -0,0 +1,182 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/logger"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiKeyMetadata    string = "x-api-key"
+	requestIDMetadata string = "x-request-id"
+)
+
+// requestIDInterceptor берёт идентификатор запроса из метаданных x-request-id
+// или генерирует новый и возвращает его клиенту в заголовке ответа.
+func requestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		requestID := firstMetadata(ctx, requestIDMetadata)
+		if !logger.ValidRequestID(requestID) {
+			requestID = logger.NewRequestID()
+		}
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+
+		return handler(logger.WithRequestID(ctx, requestID), req)
+	}
+}
+
+// tracingInterceptor продолжает трассировку из метаданных traceparent или
+// начинает новую.
+func tracingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+		service, method := splitFullMethod(info.FullMethod)
+		ctx, span := tracing.StartKind(ctx, info.FullMethod, trace.SpanKindServer,
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(method),
+		)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if isServerError(code) {
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+
+		return resp, err
+	}
+}
+
+func requestLogInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		startedAt := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		level := slog.LevelInfo
+		if isServerError(code) {
+			level = slog.LevelError
+		}
+
+		slog.Log(ctx, level, "grpc request",
+			"method", info.FullMethod,
+			"code", code.String(),
+			"duration_ms", time.Since(startedAt).Milliseconds(),
+		)
+
+		return resp, err
+	}
+}
+
+func metricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		startedAt := time.Now()
+
+		resp, err := handler(ctx, req)
+		metrics.ObserveGRPCRequest(info.FullMethod, status.Code(err).String(), time.Since(startedAt))
+
+		return resp, err
+	}
+}
+
+func recoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.ErrorContext(ctx, "grpc handler panicked", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, codes.Internal.String())
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// authInterceptor проверяет API ключ из метаданных x-api-key для каждого
+// вызова.
+func (s *Server) authInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		op := "grpc.interceptors.authInterceptor"
+
+		apiKey, err := s.apiKeyRepository.VerificationAPIKey(ctx, firstMetadata(ctx, apiKeyMetadata))
+		if err != nil {
+			return nil, toStatus(ctx, op, err)
+		}
+
+		if !apiKey.Valid {
+			return nil, toStatus(ctx, op, internal.ErrUnauthorized)
+		}
+
+		return handler(internal.ContextWithAPIKey(ctx, apiKey), req)
+	}
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	values := metadata.ValueFromIncomingContext(ctx, key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func splitFullMethod(fullMethod string) (string, string) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return service, method
+}
+
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded:
+		return true
+	}
+
+	return false
+}
+
+// metadataCarrier позволяет propagation читать traceparent из метаданных.
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	values := metadata.MD(mc).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (mc metadataCarrier) Set(key, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for key := range mc {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
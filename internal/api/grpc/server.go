@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/sashaem1/ExchangeRate/internal/api/grpc/exchangeratev1"
+	apihttp "github.com/sashaem1/ExchangeRate/internal/api/http"
+	"google.golang.org/grpc"
+)
+
+// Server обслуживает gRPC api поверх тех же репозиториев, что и http.Server.
+type Server struct {
+	exchangeratev1.UnimplementedExchangeRateServiceServer
+
+	grpcServer          *grpc.Server
+	exchangeRepository  apihttp.ExchangeRepository
+	apiKeyRepository    apihttp.APIKeyRepository
+	actionLogRepository apihttp.ActionLogRepository
+}
+
+func NewServer(exchangeRepository apihttp.ExchangeRepository, apiKeyRepository apihttp.APIKeyRepository, actionLogRepository apihttp.ActionLogRepository) *Server {
+	s := &Server{
+		exchangeRepository:  exchangeRepository,
+		apiKeyRepository:    apiKeyRepository,
+		actionLogRepository: actionLogRepository,
+	}
+
+	s.grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		requestIDInterceptor(),
+		tracingInterceptor(),
+		requestLogInterceptor(),
+		metricsInterceptor(),
+		recoveryInterceptor(),
+		s.authInterceptor(),
+	))
+	exchangeratev1.RegisterExchangeRateServiceServer(s.grpcServer, s)
+
+	return s
+}
+
+func (s *Server) Start(port string) error {
+	op := "grpc.server.Start"
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.grpcServer.Serve(listener)
+	if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Shutdown перестаёт принимать новые вызовы и ждёт завершения текущих. Если
+// ctx истекает раньше, оставшиеся вызовы прерываются.
+func (s *Server) Shutdown(ctx context.Context) error {
+	op := "grpc.server.Shutdown"
+
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/api/grpc/exchangeratev1"
+)
+
+const dateFormat string = "2006-01-02"
+
+func (s *Server) GetRate(ctx context.Context, req *exchangeratev1.GetRateRequest) (*exchangeratev1.GetRateResponse, error) {
+	op := "grpc.service.GetRate"
+	s.logAction(ctx, op, internal.ActionLogRatePair, map[string]any{"base": req.GetBase(), "symbols": req.GetSymbols()})
+
+	exchanges, err := s.exchangeRepository.GetLatest(ctx, req.GetBase(), req.GetSymbols())
+	if err != nil {
+		return nil, toStatus(ctx, op, err)
+	}
+
+	return &exchangeratev1.GetRateResponse{
+		Base:  exchanges[0].BaseCurrency.Code,
+		Rates: newRates(exchanges, pricingProfile(ctx)),
+	}, nil
+}
+
+func (s *Server) GetRatesByDate(ctx context.Context, req *exchangeratev1.GetRatesByDateRequest) (*exchangeratev1.GetRatesByDateResponse, error) {
+	op := "grpc.service.GetRatesByDate"
+	s.logAction(ctx, op, internal.ActionLogRateDate, map[string]any{"date": req.GetDate(), "mode": req.GetMode()})
+
+	mode, err := internal.ParseDateMode(req.GetMode())
+	if err != nil {
+		return nil, toStatus(ctx, op, err)
+	}
+
+	rates, err := s.exchangeRepository.GetByDate(ctx, req.GetDate(), mode)
+	if err != nil {
+		return nil, toStatus(ctx, op, err)
+	}
+
+	return &exchangeratev1.GetRatesByDateResponse{
+		Date:          req.GetDate(),
+		EffectiveDate: rates.EffectiveDate.Format(dateFormat),
+		Rates:         newRates(rates.Exchanges, pricingProfile(ctx)),
+	}, nil
+}
+
+func (s *Server) Convert(ctx context.Context, req *exchangeratev1.ConvertRequest) (*exchangeratev1.ConvertResponse, error) {
+	op := "grpc.service.Convert"
+	s.logAction(ctx, op, internal.ActionLogRateConvert,
+		map[string]any{"from": req.GetFrom(), "to": req.GetTo(), "amount": req.GetAmount(), "date": req.GetDate()})
+
+	conversion, err := s.exchangeRepository.Convert(ctx, req.GetFrom(), req.GetTo(), req.GetAmount(), req.GetDate())
+	if err != nil {
+		return nil, toStatus(ctx, op, err)
+	}
+
+	priced := pricingProfile(ctx).Convert(conversion)
+
+	return &exchangeratev1.ConvertResponse{
+		From:      priced.From.Code,
+		To:        priced.To.Code,
+		Amount:    priced.Amount,
+		Result:    priced.Result,
+		Rate:      priced.Rate,
+		Date:      priced.Date.Format(dateFormat),
+		Bid:       priced.Quote.Bid,
+		Ask:       priced.Quote.Ask,
+		Mid:       priced.Quote.Mid,
+		BidResult: priced.BidResult,
+		AskResult: priced.AskResult,
+	}, nil
+}
+
+func (s *Server) GetTimeSeries(ctx context.Context, req *exchangeratev1.GetTimeSeriesRequest) (*exchangeratev1.GetTimeSeriesResponse, error) {
+	op := "grpc.service.GetTimeSeries"
+	s.logAction(ctx, op, internal.ActionLogRateTimeSeries,
+		map[string]any{"base": req.GetBase(), "symbols": req.GetSymbols(), "start": req.GetStart(), "end": req.GetEnd()})
+
+	exchanges, err := s.exchangeRepository.GetTimeSeries(ctx, req.GetBase(), req.GetSymbols(), req.GetStart(), req.GetEnd())
+	if err != nil {
+		return nil, toStatus(ctx, op, err)
+	}
+
+	return &exchangeratev1.GetTimeSeriesResponse{
+		Base:  req.GetBase(),
+		Start: req.GetStart(),
+		End:   req.GetEnd(),
+		Rates: newRates(exchanges, pricingProfile(ctx)),
+	}, nil
+}
+
+func (s *Server) logAction(ctx context.Context, op string, actionType internal.ActionLogType, payload map[string]any) {
+	payload["transport"] = "grpc"
+
+	actionLog, err := internal.NewActionLog(actionType.Action, firstMetadata(ctx, apiKeyMetadata), payload, time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "building action log failed", "op", op, "error", err)
+		return
+	}
+
+	err = s.actionLogRepository.InsertLog(ctx, actionLog)
+	if err != nil {
+		slog.WarnContext(ctx, "action log entry dropped", "op", op, "error", err)
+	}
+}
+
+// pricingProfile возвращает профиль наценки ключа, проверенного
+// authInterceptor.
+func pricingProfile(ctx context.Context) *internal.PricingProfile {
+	apiKey, _ := internal.APIKeyFromContext(ctx)
+	return apiKey.Pricing
+}
+
+func newRates(exchanges []internal.Exchange, pricing *internal.PricingProfile) []*exchangeratev1.Rate {
+	rates := make([]*exchangeratev1.Rate, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		quote := pricing.Quote(exchange.BaseCurrency.Code, exchange.TargetCurrency.Code, exchange.Rate)
+
+		rates = append(rates, &exchangeratev1.Rate{
+			Base:   exchange.BaseCurrency.Code,
+			Target: exchange.TargetCurrency.Code,
+			Rate:   exchange.Rate,
+			Date:   exchange.Timestamp.Format(dateFormat),
+			Bid:    quote.Bid,
+			Ask:    quote.Ask,
+			Mid:    quote.Mid,
+		})
+	}
+
+	return rates
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+const (
+	// Курсы за прошедший день больше не меняются.
+	cacheControlClosed string = "public, max-age=31536000, immutable"
+	// Текущие курсы обновляются планировщиком раз в день, но могут быть
+	// догружены в любой момент.
+	cacheControlToday string = "public, max-age=300"
+	// Во временном ряду за прошлые дни могут появиться пропущенные дни.
+	cacheControlRange string = "public, max-age=3600"
+	// Bid и ask зависят от профиля наценки ключа, который можно поменять в
+	// любой момент, поэтому такие ответы не кэшируются надолго и в общих кэшах.
+	cacheControlPriced string = "private, max-age=300"
+)
+
+// cacheControlForDate выбирает политику кэширования по дате курсов. Курсы
+// считаются закрытыми, только когда дата раньше текущей даты курсов clock:
+// до времени публикации наблюдения в течение дня ещё перезаписывают курсы
+// предыдущего дня.
+func cacheControlForDate(clock internal.Clock, date time.Time) string {
+	if date.Before(clock.RateDate(clock.Now())) {
+		return cacheControlClosed
+	}
+
+	return cacheControlToday
+}
+
+// rateETag считает ETag по набору курсов и формату ответа. Курсы
+// сортируются, поэтому порядок строк из бд на ETag не влияет.
+func rateETag(format string, rates []RateDTO) string {
+	lines := make([]string, 0, len(rates))
+	for _, rate := range rates {
+		lines = append(lines, rate.Date+"|"+rate.Base+"|"+rate.Target+"|"+formatRate(rate.Rate)+"|"+formatRate(rate.Bid)+"|"+formatRate(rate.Ask))
+	}
+	sort.Strings(lines)
+
+	hash := sha256.New()
+	hash.Write([]byte(format + "\n"))
+	for _, line := range lines {
+		hash.Write([]byte(line + "\n"))
+	}
+
+	return `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+}
+
+// lastFetched возвращает самый поздний момент получения курсов.
+func lastFetched(exchanges []internal.Exchange) time.Time {
+	var last time.Time
+	for _, exchange := range exchanges {
+		if exchange.FetchedAt.After(last) {
+			last = exchange.FetchedAt
+		}
+	}
+
+	return last
+}
+
+// writeCacheHeaders выставляет ETag, Last-Modified и Cache-Control и
+// отвечает 304, если у клиента актуальная версия. В этом случае вызывающий
+// не должен писать тело ответа.
+func writeCacheHeaders(c *gin.Context, etag string, lastModified time.Time, cacheControl string) bool {
+	if pricingProfile(c) != nil {
+		cacheControl = cacheControlPriced
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// notModified проверяет условные заголовки по RFC 9110: If-None-Match
+// важнее If-Modified-Since.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err == nil && !lastModified.Truncate(time.Second).After(since) {
+			return true
+		}
+	}
+
+	return false
+}
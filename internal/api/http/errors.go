@@ -0,0 +1,97 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/i18n"
+)
+
+type ErrorResponse struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+var errorStatuses = map[internal.ErrorCode]int{
+	internal.CodeInvalidArgument:      http.StatusBadRequest,
+	internal.CodeMissingParameter:     http.StatusBadRequest,
+	internal.CodeInvalidCurrencyCode:  http.StatusBadRequest,
+	internal.CodeSameCurrency:         http.StatusBadRequest,
+	internal.CodeInvalidRate:          http.StatusBadRequest,
+	internal.CodeInvalidAmount:        http.StatusBadRequest,
+	internal.CodeInvalidActionType:    http.StatusBadRequest,
+	internal.CodeUnknownActionType:    http.StatusBadRequest,
+	internal.CodeInvalidTimeRange:     http.StatusBadRequest,
+	internal.CodeRangeTooLarge:        http.StatusBadRequest,
+	internal.CodeUnsupportedFormat:    http.StatusNotAcceptable,
+	internal.CodeInvalidDate:          http.StatusBadRequest,
+	internal.CodeUnauthorized:         http.StatusUnauthorized,
+	internal.CodeNotFound:             http.StatusNotFound,
+	internal.CodeSubscriptionNotFound: http.StatusNotFound,
+	internal.CodeNotBusinessDay:       http.StatusNotFound,
+	internal.CodeInvalidWebhookURL:    http.StatusBadRequest,
+	internal.CodeUnsupportedCurrency:  http.StatusUnprocessableEntity,
+	internal.CodeDateInFuture:         http.StatusUnprocessableEntity,
+	internal.CodeDateTooEarly:         http.StatusUnprocessableEntity,
+	internal.CodeUpstreamError:        http.StatusBadGateway,
+	internal.CodeUpstreamUnavailable:  http.StatusServiceUnavailable,
+}
+
+// writeError отвечает клиенту кодом и статусом ошибки предметной области,
+// сообщение переводится на язык запроса. Остальные ошибки считаются
+// внутренними: клиент получает только код internal, а подробности остаются
+// в логе.
+func writeError(c *gin.Context, op string, err error) {
+	ctx := c.Request.Context()
+	lang := requestLang(c)
+	c.Header("Content-Language", string(lang))
+
+	var domainErr *internal.Error
+	if !errors.As(err, &domainErr) {
+		slog.ErrorContext(ctx, "request failed", "op", op, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    string(internal.CodeInternal),
+			Message: i18n.Message(lang, string(internal.CodeInternal), nil),
+		})
+		return
+	}
+
+	status, ok := errorStatuses[domainErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "request failed", "op", op, "code", domainErr.Code, "error", err)
+	} else {
+		slog.InfoContext(ctx, "request rejected", "op", op, "code", domainErr.Code, "error", err)
+	}
+
+	c.AbortWithStatusJSON(status, ErrorResponse{
+		Code:    string(domainErr.Code),
+		Message: i18n.Message(lang, string(domainErr.Code), domainErr.Details),
+		Details: domainErr.Details,
+	})
+}
+
+// requestLang выбирает язык сообщений по параметру lang или заголовку
+// Accept-Language.
+func requestLang(c *gin.Context) i18n.Lang {
+	return i18n.Negotiate(c.Query("lang"), c.GetHeader("Accept-Language"))
+}
+
+// requireQuery возвращает ошибку missing_parameter для первого
+// незаполненного параметра запроса.
+func requireQuery(c *gin.Context, names ...string) error {
+	for _, name := range names {
+		if c.Query(name) == "" {
+			return internal.ErrMissingParameter.With(map[string]any{"name": name})
+		}
+	}
+
+	return nil
+}
@@ -2,12 +2,15 @@ package http
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
 )
 
 type RateResponse struct {
@@ -25,13 +28,37 @@ func NewHandler(server *Server) *Handler {
 
 func (h *Handler) InitRouters() *gin.Engine {
 	router := gin.New()
+	router.Use(requestIDMiddleware(), tracingMiddleware(), requestLogMiddleware(), gin.Recovery(), metricsMiddleware())
 
+	router.GET("/healthz", h.getLiveness)
+	router.GET("/readyz", h.getReadiness)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	routes := h.v1Routes()
+	spec := newOpenAPISpec(routes)
+
+	v1 := router.Group(apiV1Prefix)
+	{
+		for _, r := range routes {
+			v1.Handle(r.Method, r.Path, r.Handler)
+		}
+
+		v1.GET("/openapi.json", func(c *gin.Context) {
+			c.JSON(http.StatusOK, spec)
+		})
+		v1.GET("/schema/rates.xsd", func(c *gin.Context) {
+			c.Data(http.StatusOK, formatContentTypes[formatXML], []byte(ratesXSD))
+		})
+	}
+
+	// Маршруты без версии оставлены для старых клиентов и отвечают в прежнем
+	// формате.
 	api := router.Group("/api")
 	{
 		rate := api.Group("/rate")
 		{
-			rate.GET("/current", h.getCurrentRateByPair)
-			rate.GET("/historical", h.getCurrentRateByDate)
+			rate.GET("/current", deprecatedMiddleware(apiV1Prefix+"/rate/current"), h.getCurrentRateByPair)
+			rate.GET("/historical", deprecatedMiddleware(apiV1Prefix+"/rate/historical"), h.getCurrentRateByDate)
 		}
 	}
 
@@ -40,85 +67,121 @@ func (h *Handler) InitRouters() *gin.Engine {
 
 func (h *Handler) getCurrentRateByPair(c *gin.Context) {
 	op := "http.handlers.getCurrentRateByBase"
-	base := c.Query("base")
-	symbol := c.Query("symbol")
-	apiKeyString := c.Query("apikey")
-	ctx := context.Background()
 
-	actionLog, err := internal.NewActionLog("pair", time.Now())
-	if err != nil {
-		log.Printf("%s: %s", op, err)
+	exchanges, ok := h.latestRates(c, op)
+	if !ok {
+		return
 	}
 
-	err = h.server.actionLogRepository.InsertLog(ctx, actionLog)
-	if err != nil {
-		log.Printf("%s: %s", op, err)
+	rate := make(map[string]float64, len(exchanges))
+	for _, exchange := range exchanges {
+		rate[exchange.TargetCurrency.Code] = exchange.Rate
 	}
 
-	apiKey, err := h.server.apiKeyRepository.VerificationAPIKey(apiKeyString)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"base": exchanges[0].BaseCurrency.Code,
+		"rate": rate,
+	})
+}
 
-	if !apiKey.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не верный API ключ"})
+func (h *Handler) getCurrentRateByDate(c *gin.Context) {
+	op := "http.handlers.getCurrentRateByDate"
+
+	ratesOnDate, ok := h.ratesByDate(c, op)
+	if !ok {
 		return
 	}
 
-	exchange, err := h.server.exchangeRepository.GetByBase(base, symbol)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	exchanges := ratesOnDate.Exchanges
+	if writeCacheHeaders(c, rateETag(formatJSON+"/legacy", newRateDTOs(exchanges, nil)), lastFetched(exchanges), cacheControlForDate(h.server.clock, ratesOnDate.Date)) {
 		return
 	}
 
+	rates := ConvertExchangesToRateResponse(exchanges)
+
 	c.JSON(http.StatusOK, gin.H{
-		"base": exchange.BaseCurrency.Code,
-		"rate": map[string]float64{
-			exchange.TargetCurrency.Code: exchange.Rate,
-		},
+		"date":           c.Query("date"),
+		"effective_date": ratesOnDate.EffectiveDate.Format("2006-01-02"),
+		"rates":          rates,
 	})
 }
 
-func (h *Handler) getCurrentRateByDate(c *gin.Context) {
-	op := "http.handlers.getCurrentRateByDate"
-	date := c.Query("date")
+// latestRates выполняет общую для всех версий api часть запроса текущих
+// курсов: логирование, проверку ключа и параметров. Валюты берутся из
+// symbols через запятую или из symbol, без них возвращаются все курсы base.
+// При ошибке ответ уже записан.
+func (h *Handler) latestRates(c *gin.Context, op string) ([]internal.Exchange, bool) {
+	base := c.Query("base")
+	symbols := querySymbols(c)
 	apiKeyString := c.Query("apikey")
-	ctx := context.Background()
+	ctx := c.Request.Context()
+
+	h.logAction(ctx, op, internal.ActionLogRatePair, apiKeyString, map[string]any{"base": base, "symbols": symbols})
 
-	actionLog, err := internal.NewActionLog("date", time.Now())
+	if !h.authorize(c, op, apiKeyString) {
+		return nil, false
+	}
+
+	if err := requireQuery(c, "base"); err != nil {
+		writeError(c, op, err)
+		return nil, false
+	}
+
+	exchanges, err := h.server.exchangeRepository.GetLatest(ctx, base, symbols)
 	if err != nil {
-		log.Printf("%s: %s", op, err)
-	} else {
-		err = h.server.actionLogRepository.InsertLog(ctx, actionLog)
-		if err != nil {
-			log.Printf("%s: %s", op, err)
+		writeError(c, op, err)
+		return nil, false
+	}
+
+	return exchanges, true
+}
+
+func querySymbols(c *gin.Context) []string {
+	value := c.Query("symbols")
+	if value == "" {
+		value = c.Query("symbol")
+	}
+
+	var symbols []string
+	for _, symbol := range strings.Split(value, ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, symbol)
 		}
 	}
 
-	apiKey, err := h.server.apiKeyRepository.VerificationAPIKey(apiKeyString)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	return symbols
+}
+
+func (h *Handler) ratesByDate(c *gin.Context, op string) (internal.RatesOnDate, bool) {
+	date := c.Query("date")
+	mode := c.Query("mode")
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	h.logAction(ctx, op, internal.ActionLogRateDate, apiKeyString, map[string]any{"date": date, "mode": mode})
+
+	if !h.authorize(c, op, apiKeyString) {
+		return internal.RatesOnDate{}, false
 	}
 
-	if !apiKey.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не верный API ключ"})
-		return
+	if err := requireQuery(c, "date"); err != nil {
+		writeError(c, op, err)
+		return internal.RatesOnDate{}, false
 	}
 
-	exchanges, err := h.server.exchangeRepository.GetByDate(date)
+	dateMode, err := internal.ParseDateMode(mode)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		writeError(c, op, err)
+		return internal.RatesOnDate{}, false
 	}
 
-	rates := ConvertExchangesToRateResponse(exchanges)
+	rates, err := h.server.exchangeRepository.GetByDate(ctx, date, dateMode)
+	if err != nil {
+		writeError(c, op, err)
+		return internal.RatesOnDate{}, false
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"date":  date,
-		"rates": rates,
-	})
+	return rates, true
 }
 
 func ConvertExchangesToRateResponse(exchanges []internal.Exchange) []RateResponse {
@@ -145,3 +208,161 @@ func ConvertExchangesToRateResponse(exchanges []internal.Exchange) []RateRespons
 
 	return result
 }
+
+type ActionLogResponse struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	APIKey    string         `json:"api_key,omitempty"`
+	Payload   map[string]any `json:"payload"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+type ActionLogListResponse struct {
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+	Logs   []ActionLogResponse `json:"logs"`
+}
+
+func (h *Handler) getActionLogs(c *gin.Context) {
+	op := "http.handlers.getActionLogs"
+
+	filter, logs, ok := h.actionLogs(c, op)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, ActionLogListResponse{
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+		Logs:   convertActionLogs(logs),
+	})
+}
+
+func (h *Handler) actionLogs(c *gin.Context, op string) (internal.ActionLogFilter, []internal.ActionLog, bool) {
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	if !h.authorize(c, op, apiKeyString) {
+		return internal.ActionLogFilter{}, nil, false
+	}
+
+	var types []string
+	if typeParam := c.Query("type"); typeParam != "" {
+		types = strings.Split(typeParam, ",")
+	}
+
+	from, err := parseLogTime(c.Query("from"), false)
+	if err != nil {
+		writeError(c, op, err)
+		return internal.ActionLogFilter{}, nil, false
+	}
+
+	to, err := parseLogTime(c.Query("to"), true)
+	if err != nil {
+		writeError(c, op, err)
+		return internal.ActionLogFilter{}, nil, false
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	// Без прав администратора ключ видит только свои действия.
+	key := c.Query("key")
+	if caller, _ := internal.APIKeyFromContext(c.Request.Context()); !caller.Admin {
+		key = apiKeyString
+	}
+
+	filter, err := internal.NewActionLogFilter(types, key, from, to, limit, offset)
+	if err != nil {
+		writeError(c, op, err)
+		return internal.ActionLogFilter{}, nil, false
+	}
+
+	h.logAction(ctx, op, internal.ActionLogLogQuery, apiKeyString, map[string]any{"type": types, "key": filter.APIKey})
+
+	logs, err := h.server.actionLogRepository.ListLogs(ctx, filter)
+	if err != nil {
+		writeError(c, op, err)
+		return internal.ActionLogFilter{}, nil, false
+	}
+
+	return filter, logs, true
+}
+
+func convertActionLogs(logs []internal.ActionLog) []ActionLogResponse {
+	result := make([]ActionLogResponse, 0, len(logs))
+	for _, l := range logs {
+		result = append(result, ActionLogResponse{
+			ID:        string(l.ID),
+			Type:      l.ActionLogType.Action,
+			APIKey:    l.APIKey,
+			Payload:   l.Payload,
+			Timestamp: l.Timestamp,
+		})
+	}
+
+	return result
+}
+
+// logAction ставит запись в лог действий. Ошибки записи не влияют на ответ
+// клиенту.
+func (h *Handler) logAction(ctx context.Context, op string, actionType internal.ActionLogType, apiKey string, payload map[string]any) {
+	actionLog, err := internal.NewActionLog(actionType.Action, apiKey, payload, time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "building action log failed", "op", op, "error", err)
+		return
+	}
+
+	err = h.server.actionLogRepository.InsertLog(ctx, actionLog)
+	if err != nil {
+		slog.WarnContext(ctx, "action log entry dropped", "op", op, "error", err)
+	}
+}
+
+// authorize проверяет API ключ и сам отвечает клиенту ошибкой, если доступ
+// запрещён.
+func (h *Handler) authorize(c *gin.Context, op, apiKeyString string) bool {
+	apiKey, err := h.server.apiKeyRepository.VerificationAPIKey(c.Request.Context(), apiKeyString)
+	if err != nil {
+		writeError(c, op, err)
+		return false
+	}
+
+	if !apiKey.Valid {
+		writeError(c, op, internal.ErrUnauthorized)
+		return false
+	}
+
+	c.Request = c.Request.WithContext(internal.ContextWithAPIKey(c.Request.Context(), apiKey))
+
+	return true
+}
+
+// pricingProfile возвращает профиль наценки ключа, проверенного authorize.
+func pricingProfile(c *gin.Context) *internal.PricingProfile {
+	apiKey, _ := internal.APIKeyFromContext(c.Request.Context())
+	return apiKey.Pricing
+}
+
+// parseLogTime принимает RFC3339 или дату "2006-01-02". Для правой границы
+// периода дата без времени включает весь день.
+func parseLogTime(value string, isUpperBound bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	parsed, err := internal.ParseDate(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if isUpperBound {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+
+	return parsed, nil
+}
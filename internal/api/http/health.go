@@ -0,0 +1,240 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+const (
+	CheckStatusOK       string = "ok"
+	CheckStatusDegraded string = "degraded"
+	CheckStatusFail     string = "fail"
+)
+
+const (
+	readinessCheckTimeout  time.Duration = 2 * time.Second
+	rateFreshnessThreshold time.Duration = 48 * time.Hour
+)
+
+// ReadinessCheck - проверка зависимости для /readyz. Провал (fail) критичной
+// проверки делает сервис неготовым. Провал некритичной и статус degraded
+// любой проверки лишь понижают общий статус до degraded.
+type ReadinessCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) CheckResult
+}
+
+type CheckResult struct {
+	Status  string
+	Details map[string]any
+	Err     error
+}
+
+type CheckResponse struct {
+	Status    string         `json:"status"`
+	Critical  bool           `json:"critical"`
+	LatencyMs float64        `json:"latency_ms"`
+	Details   map[string]any `json:"details,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type CircuitReporter interface {
+	CircuitState() string
+}
+
+func PingCheck(name string, pinger Pinger) ReadinessCheck {
+	return ReadinessCheck{
+		Name:     name,
+		Critical: true,
+		Check: func(ctx context.Context) CheckResult {
+			err := pinger.Ping(ctx)
+			if err != nil {
+				return CheckResult{Status: CheckStatusFail, Err: err}
+			}
+
+			return CheckResult{Status: CheckStatusOK}
+		},
+	}
+}
+
+// CircuitCheck не критична: при открытой цепи сервис продолжает отвечать
+// курсами из бд.
+func CircuitCheck(name string, reporter CircuitReporter) ReadinessCheck {
+	return ReadinessCheck{
+		Name: name,
+		Check: func(ctx context.Context) CheckResult {
+			state := reporter.CircuitState()
+			status := CheckStatusOK
+			if state != "closed" {
+				status = CheckStatusDegraded
+			}
+
+			return CheckResult{Status: status, Details: map[string]any{"circuit": state}}
+		},
+	}
+}
+
+func initCheck(repository ExchangeRepository) ReadinessCheck {
+	return ReadinessCheck{
+		Name:     "initial_data",
+		Critical: true,
+		Check: func(ctx context.Context) CheckResult {
+			initStatus := repository.InitStatus()
+			details := map[string]any{
+				"state":           initStatus.State,
+				"total_dates":     initStatus.TotalDates,
+				"completed_dates": initStatus.CompletedDates,
+				"failed_dates":    initStatus.FailedDates,
+			}
+
+			var err error
+			if initStatus.LastError != "" {
+				err = errors.New(initStatus.LastError)
+			}
+
+			// Исторические даты загружаются и повторяются в фоне, а запросы
+			// обслуживаются по тому, что уже есть в бд, поэтому ни идущая
+			// загрузка, ни сбой стороннего апи при старте не делают сервис
+			// неготовым.
+			if initStatus.Ready() {
+				return CheckResult{Status: CheckStatusOK, Details: details}
+			}
+
+			switch initStatus.State {
+			case internal.ExchangeInitPending, internal.ExchangeInitRunning:
+				return CheckResult{Status: CheckStatusDegraded, Details: details, Err: err}
+			case internal.ExchangeInitRetrying:
+				details["next_retry_at"] = initStatus.NextRetryAt.UTC().Format(time.RFC3339)
+				return CheckResult{Status: CheckStatusDegraded, Details: details, Err: err}
+			}
+
+			return CheckResult{Status: CheckStatusFail, Details: details, Err: err}
+		},
+	}
+}
+
+func schedulerCheck(repository ExchangeRepository) ReadinessCheck {
+	return ReadinessCheck{
+		Name:     "scheduler",
+		Critical: true,
+		Check: func(ctx context.Context) CheckResult {
+			running := repository.SchedulerRunning()
+			details := map[string]any{"running": running}
+
+			if !running {
+				return CheckResult{Status: CheckStatusFail, Details: details}
+			}
+
+			return CheckResult{Status: CheckStatusOK, Details: details}
+		},
+	}
+}
+
+func rateFreshnessCheck(repository ExchangeRepository) ReadinessCheck {
+	return ReadinessCheck{
+		Name: "rate_freshness",
+		Check: func(ctx context.Context) CheckResult {
+			latest, err := repository.LatestRateTimestamp(ctx)
+			if err != nil {
+				return CheckResult{Status: CheckStatusFail, Err: err}
+			}
+
+			if latest.IsZero() {
+				return CheckResult{Status: CheckStatusDegraded, Details: map[string]any{"latest": nil}}
+			}
+
+			age := time.Since(latest)
+			details := map[string]any{
+				"latest":      latest.Format(time.DateOnly),
+				"age_seconds": int64(age.Seconds()),
+			}
+
+			if age > rateFreshnessThreshold {
+				return CheckResult{Status: CheckStatusDegraded, Details: details}
+			}
+
+			return CheckResult{Status: CheckStatusOK, Details: details}
+		},
+	}
+}
+
+func (h *Handler) getLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":         CheckStatusOK,
+		"uptime_seconds": int64(time.Since(h.server.startedAt).Seconds()),
+	})
+}
+
+func (h *Handler) getReadiness(c *gin.Context) {
+	checks := h.server.readinessChecks
+	results := make(map[string]CheckResponse, len(checks))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			response := runReadinessCheck(c.Request.Context(), check)
+
+			mu.Lock()
+			results[check.Name] = response
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	overall := CheckStatusOK
+	for _, result := range results {
+		switch {
+		case result.Status == CheckStatusOK:
+		case result.Critical && result.Status == CheckStatusFail:
+			overall = CheckStatusFail
+		case overall == CheckStatusOK:
+			overall = CheckStatusDegraded
+		}
+	}
+
+	status := http.StatusOK
+	if overall == CheckStatusFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, gin.H{
+		"status": overall,
+		"checks": results,
+	})
+}
+
+func runReadinessCheck(ctx context.Context, check ReadinessCheck) CheckResponse {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	result := check.Check(ctx)
+	latency := time.Since(startedAt)
+
+	response := CheckResponse{
+		Status:    result.Status,
+		Critical:  check.Critical,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+		Details:   result.Details,
+	}
+	if result.Err != nil {
+		response.Error = result.Err.Error()
+	}
+
+	return response
+}
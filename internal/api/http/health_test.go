@@ -0,0 +1,40 @@
+package http
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+// initStatusRepository отдаёт заданное состояние заполнения, остальные
+// методы в тесте не используются.
+type initStatusRepository struct {
+	ExchangeRepository
+	status internal.ExchangeInitStatus
+}
+
+func (r *initStatusRepository) InitStatus() internal.ExchangeInitStatus {
+	return r.status
+}
+
+func TestInitCheck(t *testing.T) {
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{internal.ExchangeInitPending, CheckStatusDegraded},
+		{internal.ExchangeInitRunning, CheckStatusDegraded},
+		{internal.ExchangeInitRetrying, CheckStatusDegraded},
+		{internal.ExchangeInitReady, CheckStatusOK},
+		{internal.ExchangeInitFailed, CheckStatusFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			check := initCheck(&initStatusRepository{status: internal.ExchangeInitStatus{State: tt.state}})
+			if got := check.Check(context.Background()).Status; got != tt.want {
+				t.Errorf("Check() status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
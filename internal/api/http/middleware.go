@@ -0,0 +1,114 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashaem1/ExchangeRate/internal/logger"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader string = "X-Request-ID"
+
+// requestIDMiddleware берёт идентификатор запроса из заголовка X-Request-ID
+// или генерирует новый, кладёт его в контекст запроса и возвращает клиенту.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !logger.ValidRequestID(requestID) {
+			requestID = logger.NewRequestID()
+		}
+
+		c.Header(requestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+	}
+}
+
+// tracingMiddleware продолжает трассировку из заголовка traceparent или
+// начинает новую. Спан называется по шаблону маршрута.
+func tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx, span := tracing.StartKind(ctx, c.Request.Method+" "+route, trace.SpanKindServer,
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(c.Request.URL.Path),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+func requestLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startedAt := time.Now()
+
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		slog.Log(c.Request.Context(), level, "http request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(startedAt).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+// metricsMiddleware использует шаблон маршрута, а не путь запроса, чтобы
+// не раздувать число меток. Ненайденные маршруты попадают под "unmatched".
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startedAt := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.ObserveHTTPRequest(route, c.Request.Method, c.Writer.Status(), time.Since(startedAt))
+	}
+}
+
+// deprecatedMiddleware помечает маршрут устаревшим по RFC 8594 и указывает
+// его замену в api v1.
+func deprecatedMiddleware(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
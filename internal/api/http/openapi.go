@@ -0,0 +1,215 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openAPIVersion string = "3.0.3"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// newOpenAPISpec строит документ OpenAPI 3 по таблице маршрутов api v1.
+// Схемы ответов выводятся из DTO через reflect по json тегам, так что
+// документ меняется вместе с типами ответов.
+func newOpenAPISpec(routes []route) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]any{}
+
+	errorRef := schemaRef(reflect.TypeOf(ErrorResponse{}), schemas)
+
+	for _, r := range routes {
+		params := r.Params
+		if r.Formats {
+			params = append(params[:len(params):len(params)], queryParam{Name: "format", Description: "Response format: json, csv, xml or ndjson; overrides the Accept header"})
+		}
+
+		parameters := make([]any, 0, len(params))
+		for _, p := range params {
+			schema := map[string]any{"type": "string"}
+			if p.Format == "int32" {
+				schema = map[string]any{"type": "integer", "format": p.Format}
+			} else if p.Format != "" {
+				schema["format"] = p.Format
+			}
+
+			in := "query"
+			if p.In != "" {
+				in = p.In
+			}
+
+			parameters = append(parameters, map[string]any{
+				"name":        p.Name,
+				"in":          in,
+				"description": p.Description,
+				"required":    p.Required || in == "path",
+				"schema":      schema,
+			})
+		}
+
+		contentType := "application/json"
+		if r.ContentType != "" {
+			contentType = r.ContentType
+		}
+
+		status := r.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		success := map[string]any{"description": http.StatusText(status)}
+		if r.Response != nil {
+			success["content"] = map[string]any{
+				contentType: map[string]any{"schema": schemaRef(reflect.TypeOf(r.Response), schemas)},
+			}
+		}
+		responses := map[string]any{strconv.Itoa(status): success}
+		errorStatuses := append(r.Errors[:len(r.Errors):len(r.Errors)], http.StatusInternalServerError)
+		if r.Formats {
+			content := responses[strconv.Itoa(http.StatusOK)].(map[string]any)["content"].(map[string]any)
+			content["text/csv"] = map[string]any{"schema": map[string]any{"type": "string"}}
+			content["application/xml"] = map[string]any{"schema": map[string]any{"type": "string", "externalDocs": map[string]any{"url": apiV1Prefix + "/schema/rates.xsd"}}}
+			content["application/x-ndjson"] = map[string]any{"schema": schemaRef(reflect.TypeOf(RateDTO{}), schemas)}
+			errorStatuses = append(errorStatuses, http.StatusNotAcceptable)
+		}
+
+		for _, status := range errorStatuses {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": http.StatusText(status),
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": errorRef},
+				},
+			}
+		}
+
+		operation := map[string]any{
+			"operationId": r.OperationID,
+			"summary":     r.Summary,
+			"parameters":  parameters,
+			"responses":   responses,
+		}
+		if r.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemaRef(reflect.TypeOf(r.Request), schemas)},
+				},
+			}
+		}
+
+		path := apiV1Prefix + openAPIPath(r.Path)
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(r.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   "ExchangeRate API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+// openAPIPath переводит параметры пути gin (":id") в вид OpenAPI ("{id}").
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// schemaRef возвращает схему типа. Именованные структуры выносятся в
+// components/schemas и подставляются ссылкой.
+func schemaRef(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaRef(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaRef(t.Elem(), schemas)}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		if _, ok := schemas[t.Name()]; !ok {
+			// Заглушка до построения схемы защищает от бесконечной рекурсии.
+			schemas[t.Name()] = map[string]any{}
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+
+	return map[string]any{}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := schemaRef(field.Type, schemas)
+		if format := field.Tag.Get("format"); format != "" {
+			schema["format"] = format
+		}
+		properties[name] = schema
+
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
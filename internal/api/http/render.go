@@ -0,0 +1,214 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+const (
+	formatJSON   string = "json"
+	formatCSV    string = "csv"
+	formatXML    string = "xml"
+	formatNDJSON string = "ndjson"
+)
+
+const ndjsonFlushEvery int = 100
+
+var formatContentTypes = map[string]string{
+	formatJSON:   "application/json; charset=utf-8",
+	formatCSV:    "text/csv; charset=utf-8",
+	formatXML:    "application/xml; charset=utf-8",
+	formatNDJSON: "application/x-ndjson",
+}
+
+var mediaTypeFormats = map[string]string{
+	"application/json":     formatJSON,
+	"application/*":        formatJSON,
+	"*/*":                  formatJSON,
+	"text/csv":             formatCSV,
+	"application/xml":      formatXML,
+	"text/xml":             formatXML,
+	"application/x-ndjson": formatNDJSON,
+	"application/ndjson":   formatNDJSON,
+}
+
+// rateCSVHeader - порядок колонок CSV, он не меняется между версиями. Новые
+// колонки добавляются только в конец.
+var rateCSVHeader = []string{"date", "base", "target", "rate", "bid", "ask"}
+
+// ratesXSD описывает XML ответ эндпоинтов курсов.
+const ratesXSD string = `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="rates">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="rate" minOccurs="0" maxOccurs="unbounded">
+          <xs:complexType>
+            <xs:simpleContent>
+              <xs:extension base="xs:decimal">
+                <xs:attribute name="date" type="xs:date" use="required"/>
+                <xs:attribute name="base" type="xs:string" use="required"/>
+                <xs:attribute name="target" type="xs:string" use="required"/>
+                <xs:attribute name="bid" type="xs:decimal" use="required"/>
+                <xs:attribute name="ask" type="xs:decimal" use="required"/>
+              </xs:extension>
+            </xs:simpleContent>
+          </xs:complexType>
+        </xs:element>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>
+`
+
+type xmlRates struct {
+	XMLName xml.Name  `xml:"rates"`
+	Rates   []xmlRate `xml:"rate"`
+}
+
+type xmlRate struct {
+	Date   string `xml:"date,attr"`
+	Base   string `xml:"base,attr"`
+	Target string `xml:"target,attr"`
+	Bid    string `xml:"bid,attr"`
+	Ask    string `xml:"ask,attr"`
+	Rate   string `xml:",chardata"`
+}
+
+// negotiateFormat выбирает формат ответа: параметр format важнее заголовка
+// Accept. Без того и другого ответ отдаётся в JSON.
+func negotiateFormat(c *gin.Context) (string, error) {
+	if format := strings.ToLower(c.Query("format")); format != "" {
+		if _, ok := formatContentTypes[format]; !ok {
+			return "", internal.ErrUnsupportedFormat.With(map[string]any{"format": format})
+		}
+		return format, nil
+	}
+
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return formatJSON, nil
+	}
+
+	best := ""
+	bestWeight := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		format, ok := mediaTypeFormats[mediaType]
+		if !ok {
+			continue
+		}
+
+		weight := 1.0
+		if q, ok := params["q"]; ok {
+			weight, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if weight > bestWeight {
+			best, bestWeight = format, weight
+		}
+	}
+
+	if best == "" {
+		return "", internal.ErrUnsupportedFormat.With(map[string]any{"format": accept})
+	}
+
+	return best, nil
+}
+
+// writeRates отдаёт курсы в выбранном формате. JSON использует payload
+// целиком, остальные форматы - только строки курсов.
+func writeRates(c *gin.Context, format string, payload any, rates []RateDTO) {
+	c.Header("Vary", "Accept")
+
+	switch format {
+	case formatCSV:
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", formatContentTypes[formatCSV])
+		writeRatesCSV(c, rates)
+	case formatXML:
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", formatContentTypes[formatXML])
+		writeRatesXML(c, rates)
+	case formatNDJSON:
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", formatContentTypes[formatNDJSON])
+		writeRatesNDJSON(c, rates)
+	default:
+		c.JSON(http.StatusOK, payload)
+	}
+}
+
+func writeRatesCSV(c *gin.Context, rates []RateDTO) {
+	w := csv.NewWriter(c.Writer)
+
+	records := make([][]string, 0, len(rates)+1)
+	records = append(records, rateCSVHeader)
+	for _, rate := range rates {
+		records = append(records, []string{rate.Date, rate.Base, rate.Target, formatRate(rate.Rate), formatRate(rate.Bid), formatRate(rate.Ask)})
+	}
+
+	if err := w.WriteAll(records); err != nil {
+		slog.WarnContext(c.Request.Context(), "writing csv response failed", "error", err)
+	}
+}
+
+func writeRatesXML(c *gin.Context, rates []RateDTO) {
+	doc := xmlRates{Rates: make([]xmlRate, 0, len(rates))}
+	for _, rate := range rates {
+		doc.Rates = append(doc.Rates, xmlRate{
+			Date:   rate.Date,
+			Base:   rate.Base,
+			Target: rate.Target,
+			Bid:    formatRate(rate.Bid),
+			Ask:    formatRate(rate.Ask),
+			Rate:   formatRate(rate.Rate),
+		})
+	}
+
+	_, err := io.WriteString(c.Writer, xml.Header)
+	if err == nil {
+		err = xml.NewEncoder(c.Writer).Encode(doc)
+	}
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "writing xml response failed", "error", err)
+	}
+}
+
+// writeRatesNDJSON пишет по курсу на строку и периодически сбрасывает буфер,
+// чтобы клиент мог обрабатывать большие периоды по мере получения.
+func writeRatesNDJSON(c *gin.Context, rates []RateDTO) {
+	encoder := json.NewEncoder(c.Writer)
+
+	for i, rate := range rates {
+		if err := encoder.Encode(rate); err != nil {
+			slog.WarnContext(c.Request.Context(), "writing ndjson response failed", "error", err)
+			return
+		}
+
+		if (i+1)%ndjsonFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
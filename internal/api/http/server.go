@@ -2,47 +2,95 @@ package http
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/broker"
 )
 
 type ExchangeRepository interface {
 	InitExchangeRepository(ctx context.Context) error
-	GetByBase(baseCurrencyCode, targetCurrencyCode string) (internal.Exchange, error)
-	GetByDate(date string) ([]internal.Exchange, error)
+	GetLatest(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string) ([]internal.Exchange, error)
+	GetByDate(ctx context.Context, date string, mode internal.DateMode) (internal.RatesOnDate, error)
+	GetMatrix(ctx context.Context, date string) (internal.RateMatrix, error)
+	GetTimeSeries(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, start, end string) ([]internal.Exchange, error)
+	GetFluctuation(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, start, end string) ([]internal.Fluctuation, error)
+	GetStats(ctx context.Context, baseCurrencyCode, targetCurrencyCode, start, end string) (internal.RateStats, error)
+	GetCandles(ctx context.Context, baseCurrencyCode, targetCurrencyCode, interval, start, end string) ([]internal.Candle, error)
+	Convert(ctx context.Context, fromCurrencyCode, toCurrencyCode string, amount float64, date string) (internal.Conversion, error)
+	InitStatus() internal.ExchangeInitStatus
+	LatestRateTimestamp(ctx context.Context) (time.Time, error)
+	SchedulerRunning() bool
 }
 
 type APIKeyRepository interface {
 	InitAPIKeyRepository(ctx context.Context) error
-	VerificationAPIKey(apiKey string) (internal.APIKey, error)
+	VerificationAPIKey(ctx context.Context, apiKey string) (internal.APIKey, error)
 }
 
 type ActionLogRepository interface {
 	InsertLog(ctx context.Context, ActionLog internal.ActionLog) error
+	ListLogs(ctx context.Context, filter internal.ActionLogFilter) ([]internal.ActionLog, error)
+}
+
+type SubscriptionRepository interface {
+	CreateSubscription(ctx context.Context, apiKey, webhookURL string, pairs []string, conditions []internal.SubscriptionCondition) (internal.Subscription, error)
+	GetSubscription(ctx context.Context, apiKey string, id internal.SubscriptionID) (internal.Subscription, error)
+	ListSubscriptions(ctx context.Context, apiKey string) ([]internal.Subscription, error)
+	DeleteSubscription(ctx context.Context, apiKey string, id internal.SubscriptionID) error
+	ListDeliveries(ctx context.Context, apiKey string, id internal.SubscriptionID) ([]internal.Delivery, error)
+}
+
+// RateBroker выдаёт подписки на обновления курсов по парам "USD/EUR".
+type RateBroker interface {
+	Subscribe(topics []string) (*broker.Subscription[internal.Exchange], error)
 }
 
 type Server struct {
+	mu                  sync.Mutex
 	httpServer          *http.Server
 	exchangeRepository  ExchangeRepository
 	apiKeyRepository    APIKeyRepository
 	actionLogRepository ActionLogRepository
+	rateBroker          RateBroker
+	subscriptions       SubscriptionRepository
+	clock               internal.Clock
+	readinessChecks     []ReadinessCheck
+	startedAt           time.Time
 }
 
-func NewServer(exchangeRepository ExchangeRepository, apiKeyRepository APIKeyRepository, actionLogRepository ActionLogRepository) *Server {
-	return &Server{
+// NewServer создаёт http сервер. По clock определяется, курсы каких дат уже
+// не меняются и могут кэшироваться надолго.
+func NewServer(exchangeRepository ExchangeRepository, apiKeyRepository APIKeyRepository, actionLogRepository ActionLogRepository, rateBroker RateBroker, subscriptions SubscriptionRepository, clock internal.Clock) *Server {
+	s := &Server{
 		exchangeRepository:  exchangeRepository,
 		apiKeyRepository:    apiKeyRepository,
 		actionLogRepository: actionLogRepository,
+		rateBroker:          rateBroker,
+		subscriptions:       subscriptions,
+		clock:               clock,
+		startedAt:           time.Now(),
 	}
+
+	s.AddReadinessCheck(initCheck(exchangeRepository))
+	s.AddReadinessCheck(schedulerCheck(exchangeRepository))
+	s.AddReadinessCheck(rateFreshnessCheck(exchangeRepository))
+
+	return s
+}
+
+func (s *Server) AddReadinessCheck(check ReadinessCheck) {
+	s.readinessChecks = append(s.readinessChecks, check)
 }
 
 func (s *Server) Start(port string, handler http.Handler) error {
 	op := "http.server.Start"
 	ctx := context.Background()
-	s.httpServer = &http.Server{
+	httpServer := &http.Server{
 		Addr:           ":" + port,
 		Handler:        handler,
 		MaxHeaderBytes: 1 << 20, // 1 MB
@@ -50,15 +98,45 @@ func (s *Server) Start(port string, handler http.Handler) error {
 		WriteTimeout:   10 * time.Second,
 	}
 
+	s.mu.Lock()
+	s.httpServer = httpServer
+	s.mu.Unlock()
+
 	err := s.exchangeRepository.InitExchangeRepository(ctx)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	err = s.apiKeyRepository.InitAPIKeyRepository(ctx)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Shutdown перестаёт принимать новые соединения и ждёт завершения текущих
+// запросов до истечения ctx.
+func (s *Server) Shutdown(ctx context.Context) error {
+	op := "http.server.Shutdown"
+
+	s.mu.Lock()
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	if httpServer == nil {
+		return nil
+	}
+
+	err := httpServer.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return s.httpServer.ListenAndServe()
+	return nil
 }
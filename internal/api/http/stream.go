@@ -0,0 +1,144 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/broker"
+)
+
+const (
+	streamHeartbeatInterval time.Duration = 15 * time.Second
+	streamWriteTimeout      time.Duration = 10 * time.Second
+)
+
+// streamRatesV1 отдаёт обновления курсов по Server-Sent Events. Ключ
+// проверяется при подключении и заново на каждом heartbeat, так что
+// отозванный ключ закрывает поток. Поток завершается событием close, если
+// клиент не успевает читать или сервер останавливается.
+func (h *Handler) streamRatesV1(c *gin.Context) {
+	op := "http.stream.streamRatesV1"
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	var pairs []string
+	for _, pair := range strings.Split(c.Query("pairs"), ",") {
+		if pair = strings.TrimSpace(pair); pair != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	h.logAction(ctx, op, internal.ActionLogRateStream, apiKeyString, map[string]any{"pairs": pairs})
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	pricing := pricingProfile(c)
+
+	if err := requireQuery(c, "pairs"); err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	pairs, err := internal.ParsePairs(pairs)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	sub, err := h.server.rateBroker.Subscribe(pairs)
+	if err != nil {
+		writeError(c, op, fmt.Errorf("%s: %w", op, err))
+		return
+	}
+	defer sub.Unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// WriteTimeout сервера рассчитан на обычные ответы, поэтому для потока
+	// срок задаётся на каждую запись отдельно.
+	rc := http.NewResponseController(c.Writer)
+	write := func(frame string) bool {
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+			slog.WarnContext(ctx, "setting stream write deadline failed", "op", op, "error", err)
+		}
+
+		_, err := c.Writer.WriteString(frame)
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			slog.InfoContext(ctx, "rate stream closed by client", "op", op, "error", err)
+			return false
+		}
+
+		return true
+	}
+
+	if !write(": subscribed " + strings.Join(pairs, ",") + "\n\n") {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	var eventID uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case exchange, ok := <-sub.C():
+			if !ok {
+				reason := "shutdown"
+				if errors.Is(sub.Err(), broker.ErrSlowConsumer) {
+					reason = "slow_consumer"
+				}
+				write(sseFrame("close", 0, map[string]string{"reason": reason}))
+				return
+			}
+
+			eventID++
+			if !write(sseFrame("rate", eventID, newRateDTO(exchange, pricing))) {
+				return
+			}
+		case <-heartbeat.C:
+			apiKey, err := h.server.apiKeyRepository.VerificationAPIKey(ctx, apiKeyString)
+			if err != nil {
+				slog.WarnContext(ctx, "stream api key check failed", "op", op, "error", err)
+			} else if !apiKey.Valid {
+				write(sseFrame("close", 0, map[string]string{"reason": string(internal.ErrUnauthorized.Code)}))
+				return
+			} else {
+				pricing = apiKey.Pricing
+			}
+
+			if !write(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
+
+func sseFrame(event string, id uint64, data any) string {
+	payload, _ := json.Marshal(data)
+
+	var frame strings.Builder
+	frame.WriteString("event: " + event + "\n")
+	if id > 0 {
+		fmt.Fprintf(&frame, "id: %d\n", id)
+	}
+	frame.WriteString("data: " + string(payload) + "\n\n")
+
+	return frame.String()
+}
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+type SubscriptionConditionDTO struct {
+	Type      string  `json:"type"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Percent   float64 `json:"percent,omitempty"`
+}
+
+type SubscriptionRequest struct {
+	URL        string                     `json:"url"`
+	Pairs      []string                   `json:"pairs"`
+	Conditions []SubscriptionConditionDTO `json:"conditions,omitempty"`
+}
+
+type SubscriptionDTO struct {
+	ID         string                     `json:"id"`
+	URL        string                     `json:"url"`
+	Pairs      []string                   `json:"pairs"`
+	Conditions []SubscriptionConditionDTO `json:"conditions"`
+	// Secret возвращается только при создании подписки.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SubscriptionListDTO struct {
+	Subscriptions []SubscriptionDTO `json:"subscriptions"`
+}
+
+type DeliveryDTO struct {
+	ID             string         `json:"id"`
+	EventKey       string         `json:"event_key"`
+	Status         string         `json:"status"`
+	Attempts       int            `json:"attempts"`
+	ResponseStatus int            `json:"response_status,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	Payload        map[string]any `json:"payload"`
+	CreatedAt      time.Time      `json:"created_at"`
+	NextAttemptAt  *time.Time     `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+}
+
+type DeliveryListDTO struct {
+	Deliveries []DeliveryDTO `json:"deliveries"`
+}
+
+var subscriptionIDParam = queryParam{Name: "id", Description: "Subscription ID", In: "path"}
+
+func (h *Handler) subscriptionRoutes() []route {
+	withNotFound := []int{http.StatusUnauthorized, http.StatusNotFound}
+
+	return []route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/subscriptions",
+			OperationID: "createSubscription",
+			Summary:     "Subscribe a webhook to rate updates, threshold crossings or daily changes",
+			Params:      []queryParam{apiKeyParam},
+			Request:     SubscriptionRequest{},
+			Status:      http.StatusCreated,
+			Response:    SubscriptionDTO{},
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity},
+			Handler:     h.createSubscription,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/subscriptions",
+			OperationID: "listSubscriptions",
+			Summary:     "Webhook subscriptions of the API key",
+			Params:      []queryParam{apiKeyParam},
+			Response:    SubscriptionListDTO{},
+			Errors:      []int{http.StatusUnauthorized},
+			Handler:     h.listSubscriptions,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/subscriptions/:id",
+			OperationID: "getSubscription",
+			Summary:     "Webhook subscription",
+			Params:      []queryParam{apiKeyParam, subscriptionIDParam},
+			Response:    SubscriptionDTO{},
+			Errors:      withNotFound,
+			Handler:     h.getSubscription,
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/subscriptions/:id",
+			OperationID: "deleteSubscription",
+			Summary:     "Delete a webhook subscription together with its delivery log",
+			Params:      []queryParam{apiKeyParam, subscriptionIDParam},
+			Status:      http.StatusNoContent,
+			Errors:      withNotFound,
+			Handler:     h.deleteSubscription,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/subscriptions/:id/deliveries",
+			OperationID: "listSubscriptionDeliveries",
+			Summary:     "Latest webhook deliveries of a subscription, newest first",
+			Params:      []queryParam{apiKeyParam, subscriptionIDParam},
+			Response:    DeliveryListDTO{},
+			Errors:      withNotFound,
+			Handler:     h.listSubscriptionDeliveries,
+		},
+	}
+}
+
+func (h *Handler) createSubscription(c *gin.Context) {
+	op := "http.subscriptions.createSubscription"
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	var request SubscriptionRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		writeError(c, op, internal.ErrInvalidArgument.With(map[string]any{"body": err.Error()}))
+		return
+	}
+
+	conditions := make([]internal.SubscriptionCondition, 0, len(request.Conditions))
+	for _, condition := range request.Conditions {
+		conditions = append(conditions, internal.SubscriptionCondition{
+			Type:      internal.SubscriptionConditionType(condition.Type),
+			Threshold: condition.Threshold,
+			Percent:   condition.Percent,
+		})
+	}
+
+	subscription, err := h.server.subscriptions.CreateSubscription(ctx, apiKeyString, request.URL, request.Pairs, conditions)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	h.logAction(ctx, op, internal.ActionLogSubscriptionCreate, apiKeyString,
+		map[string]any{"id": subscription.ID, "pairs": subscription.Pairs})
+
+	response := newSubscriptionDTO(subscription)
+	response.Secret = subscription.Secret
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) listSubscriptions(c *gin.Context) {
+	op := "http.subscriptions.listSubscriptions"
+	apiKeyString := c.Query("apikey")
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	subscriptions, err := h.server.subscriptions.ListSubscriptions(c.Request.Context(), apiKeyString)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	response := SubscriptionListDTO{Subscriptions: make([]SubscriptionDTO, 0, len(subscriptions))}
+	for _, subscription := range subscriptions {
+		response.Subscriptions = append(response.Subscriptions, newSubscriptionDTO(subscription))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) getSubscription(c *gin.Context) {
+	op := "http.subscriptions.getSubscription"
+	apiKeyString := c.Query("apikey")
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	subscription, err := h.server.subscriptions.GetSubscription(c.Request.Context(), apiKeyString, internal.SubscriptionID(c.Param("id")))
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSubscriptionDTO(subscription))
+}
+
+func (h *Handler) deleteSubscription(c *gin.Context) {
+	op := "http.subscriptions.deleteSubscription"
+	apiKeyString := c.Query("apikey")
+	id := internal.SubscriptionID(c.Param("id"))
+	ctx := c.Request.Context()
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	err := h.server.subscriptions.DeleteSubscription(ctx, apiKeyString, id)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	h.logAction(ctx, op, internal.ActionLogSubscriptionDelete, apiKeyString, map[string]any{"id": id})
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) listSubscriptionDeliveries(c *gin.Context) {
+	op := "http.subscriptions.listSubscriptionDeliveries"
+	apiKeyString := c.Query("apikey")
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	deliveries, err := h.server.subscriptions.ListDeliveries(c.Request.Context(), apiKeyString, internal.SubscriptionID(c.Param("id")))
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	response := DeliveryListDTO{Deliveries: make([]DeliveryDTO, 0, len(deliveries))}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, newDeliveryDTO(delivery))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func newSubscriptionDTO(subscription internal.Subscription) SubscriptionDTO {
+	conditions := make([]SubscriptionConditionDTO, 0, len(subscription.Conditions))
+	for _, condition := range subscription.Conditions {
+		conditions = append(conditions, SubscriptionConditionDTO{
+			Type:      string(condition.Type),
+			Threshold: condition.Threshold,
+			Percent:   condition.Percent,
+		})
+	}
+
+	return SubscriptionDTO{
+		ID:         string(subscription.ID),
+		URL:        subscription.URL,
+		Pairs:      subscription.Pairs,
+		Conditions: conditions,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+func newDeliveryDTO(delivery internal.Delivery) DeliveryDTO {
+	dto := DeliveryDTO{
+		ID:             string(delivery.ID),
+		EventKey:       delivery.EventKey,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+
+	_ = json.Unmarshal(delivery.Payload, &dto.Payload)
+
+	if delivery.Status == internal.DeliveryPending {
+		dto.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if !delivery.DeliveredAt.IsZero() {
+		dto.DeliveredAt = &delivery.DeliveredAt
+	}
+
+	return dto
+}
@@ -0,0 +1,670 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+const apiV1Prefix string = "/api/v1"
+
+// route описывает маршрут api v1. Из одной таблицы маршрутов строятся и
+// обработчики gin, и документ OpenAPI, поэтому они не расходятся.
+type route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Params      []queryParam
+	// Request - тело запроса в JSON, nil если тела нет.
+	Request any
+	// Status успешного ответа, по умолчанию 200. Для 204 Response не нужен.
+	Status   int
+	Response any
+	Errors   []int
+	// Formats означает, что кроме JSON маршрут отдаёт курсы в CSV, XML и
+	// NDJSON по параметру format или заголовку Accept.
+	Formats bool
+	// ContentType заменяет application/json в описании успешного ответа.
+	ContentType string
+	Handler     gin.HandlerFunc
+}
+
+type queryParam struct {
+	Name        string
+	Description string
+	Required    bool
+	Format      string
+	// In - "query" по умолчанию или "path" для параметров вида :id.
+	In string
+}
+
+var apiKeyParam = queryParam{Name: "apikey", Description: "API key", Required: true}
+
+// RateDTO - курс пары. Rate и Mid - курс из хранилища, Bid и Ask - курсы с
+// наценкой профиля API ключа; у ключа без профиля все три совпадают.
+type RateDTO struct {
+	Base   string  `json:"base"`
+	Target string  `json:"target"`
+	Rate   float64 `json:"rate"`
+	Bid    float64 `json:"bid"`
+	Ask    float64 `json:"ask"`
+	Mid    float64 `json:"mid"`
+	Date   string  `json:"date" format:"date"`
+}
+
+type LatestRatesDTO struct {
+	Base  string    `json:"base"`
+	Rates []RateDTO `json:"rates"`
+}
+
+type TimeSeriesDTO struct {
+	Base  string    `json:"base"`
+	Start string    `json:"start" format:"date"`
+	End   string    `json:"end" format:"date"`
+	Rates []RateDTO `json:"rates"`
+}
+
+type RateMatrixDTO struct {
+	Date       string                        `json:"date" format:"date"`
+	Currencies []string                      `json:"currencies"`
+	Rates      map[string]map[string]float64 `json:"rates"`
+}
+
+// FluctuationDTO - изменение курса пары. StartDate и EndDate - рабочие дни
+// пары, курсы которых сравниваются.
+type FluctuationDTO struct {
+	Target        string  `json:"target"`
+	StartDate     string  `json:"start_date" format:"date"`
+	EndDate       string  `json:"end_date" format:"date"`
+	StartRate     float64 `json:"start_rate"`
+	EndRate       float64 `json:"end_rate"`
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+type FluctuationListDTO struct {
+	Base  string           `json:"base"`
+	Start string           `json:"start" format:"date"`
+	End   string           `json:"end" format:"date"`
+	Rates []FluctuationDTO `json:"rates"`
+}
+
+// ConversionDTO - пересчёт суммы. Result считается по mid, BidResult и
+// AskResult - по курсам с наценкой профиля ключа; у ключа без профиля все
+// три совпадают.
+type ConversionDTO struct {
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Amount    float64 `json:"amount"`
+	Date      string  `json:"date" format:"date"`
+	Rate      float64 `json:"rate"`
+	Bid       float64 `json:"bid"`
+	Ask       float64 `json:"ask"`
+	Mid       float64 `json:"mid"`
+	Result    float64 `json:"result"`
+	BidResult float64 `json:"bid_result"`
+	AskResult float64 `json:"ask_result"`
+}
+
+type RateStatsDTO struct {
+	Base          string  `json:"base"`
+	Target        string  `json:"target"`
+	Start         string  `json:"start" format:"date"`
+	End           string  `json:"end" format:"date"`
+	Count         int     `json:"count"`
+	Min           float64 `json:"min"`
+	Max           float64 `json:"max"`
+	Mean          float64 `json:"mean"`
+	Median        float64 `json:"median"`
+	StdDev        float64 `json:"stddev"`
+	First         float64 `json:"first"`
+	Last          float64 `json:"last"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+type CandleDTO struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Open  float64   `json:"open"`
+	High  float64   `json:"high"`
+	Low   float64   `json:"low"`
+	Close float64   `json:"close"`
+	Count int       `json:"count"`
+}
+
+type CandleListDTO struct {
+	Base     string      `json:"base"`
+	Target   string      `json:"target"`
+	Interval string      `json:"interval"`
+	Candles  []CandleDTO `json:"candles"`
+}
+
+type RatesByDateDTO struct {
+	Date string `json:"date" format:"date"`
+	// EffectiveDate - самый поздний из рабочих дней пар, курсы которых
+	// возвращены; у каждого курса своя дата.
+	EffectiveDate string    `json:"effective_date" format:"date"`
+	Rates         []RateDTO `json:"rates"`
+}
+
+func (h *Handler) v1Routes() []route {
+	return append([]route{
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/current",
+			OperationID: "getRate",
+			Summary:     "Current rates of a base currency",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "base", Description: "Base currency code, e.g. USD", Required: true},
+				{Name: "symbols", Description: "Comma separated target currency codes, e.g. EUR,RUB; all supported currencies when omitted"},
+				{Name: "symbol", Description: "Single target currency code, kept for compatibility with symbols"},
+			},
+			Response: LatestRatesDTO{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity, http.StatusBadGateway, http.StatusServiceUnavailable},
+			Formats:  true,
+			Handler:  h.getRateV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/historical",
+			OperationID: "getRatesByDate",
+			Summary:     "Rates of all currency pairs on a date",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "date", Description: "Date in YYYY-MM-DD format", Required: true, Format: "date"},
+				{Name: "mode", Description: "previous (default) returns the rates of the previous business day for weekends and holidays, strict responds 404 instead"},
+			},
+			Response: RatesByDateDTO{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusBadGateway, http.StatusServiceUnavailable},
+			Formats:  true,
+			Handler:  h.getRatesByDateV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/timeseries",
+			OperationID: "getTimeSeries",
+			Summary:     "Stored rates of a base currency for every day of a period",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "base", Description: "Base currency code, e.g. USD", Required: true},
+				{Name: "symbols", Description: "Comma separated target currency codes; all supported currencies when omitted"},
+				{Name: "start", Description: "First day of the period, YYYY-MM-DD", Required: true, Format: "date"},
+				{Name: "end", Description: "Last day of the period, YYYY-MM-DD, at most 366 days after start", Required: true, Format: "date"},
+			},
+			Response: TimeSeriesDTO{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity},
+			Formats:  true,
+			Handler:  h.getTimeSeriesV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/fluctuation",
+			OperationID: "getFluctuation",
+			Summary:     "Absolute and percent change of rates between two dates",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "base", Description: "Base currency code, e.g. USD", Required: true},
+				{Name: "symbols", Description: "Comma separated target currency codes; all supported currencies when omitted"},
+				{Name: "start", Description: "First date, YYYY-MM-DD", Required: true, Format: "date"},
+				{Name: "end", Description: "Second date, YYYY-MM-DD, at most 366 days after start", Required: true, Format: "date"},
+			},
+			Response: FluctuationListDTO{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusBadGateway, http.StatusServiceUnavailable},
+			Handler:  h.getFluctuationV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/convert",
+			OperationID: "convert",
+			Summary:     "Convert an amount at the mid, bid and ask rates",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "from", Description: "Currency code of the amount, e.g. USD", Required: true},
+				{Name: "to", Description: "Target currency code, e.g. RUB", Required: true},
+				{Name: "amount", Description: "Positive amount in the from currency", Required: true},
+				{Name: "date", Description: "Rate date, YYYY-MM-DD; the current rate when omitted", Format: "date"},
+			},
+			Response: ConversionDTO{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusBadGateway, http.StatusServiceUnavailable},
+			Handler:  h.convertV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/stats",
+			OperationID: "getRateStats",
+			Summary:     "Min, max, mean, median, standard deviation and change of a pair over a period",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "base", Description: "Base currency code, e.g. USD", Required: true},
+				{Name: "symbol", Description: "Target currency code, e.g. RUB", Required: true},
+				{Name: "start", Description: "First day of the period, YYYY-MM-DD", Required: true, Format: "date"},
+				{Name: "end", Description: "Last day of the period, YYYY-MM-DD, at most 366 days after start", Required: true, Format: "date"},
+			},
+			Response: RateStatsDTO{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity},
+			Handler:  h.getRateStatsV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/ohlc",
+			OperationID: "getRateCandles",
+			Summary:     "Open, high, low and close of a pair per hour, day, week or month",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "base", Description: "Base currency code, e.g. USD", Required: true},
+				{Name: "symbol", Description: "Target currency code, e.g. RUB", Required: true},
+				{Name: "interval", Description: "Candle interval: hour, day, week or month; day when omitted"},
+				{Name: "start", Description: "First day of the period, YYYY-MM-DD", Required: true, Format: "date"},
+				{Name: "end", Description: "Last day of the period, YYYY-MM-DD, at most 366 days after start", Required: true, Format: "date"},
+			},
+			Response: CandleListDTO{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity},
+			Handler:  h.getCandlesV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/matrix",
+			OperationID: "getRateMatrix",
+			Summary:     "Cross-rate table of all supported currencies on a date",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "date", Description: "Date in YYYY-MM-DD format, today when omitted", Format: "date"},
+			},
+			Response: RateMatrixDTO{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity, http.StatusBadGateway, http.StatusServiceUnavailable},
+			Handler:  h.getRateMatrixV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/rate/stream",
+			OperationID: "streamRates",
+			Summary:     "Server-Sent Events stream of new and changed rates",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "pairs", Description: "Comma separated currency pairs, e.g. USD/EUR,EUR/RUB", Required: true},
+			},
+			Response:    RateDTO{},
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity},
+			ContentType: "text/event-stream",
+			Handler:     h.streamRatesV1,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/log",
+			OperationID: "listActionLogs",
+			Summary:     "Action log entries",
+			Params: []queryParam{
+				apiKeyParam,
+				{Name: "type", Description: "Comma separated action types, e.g. rate.pair or rate.*"},
+				{Name: "key", Description: "API key the action was performed with"},
+				{Name: "from", Description: "Start of the period, RFC3339 or YYYY-MM-DD"},
+				{Name: "to", Description: "End of the period, RFC3339 or YYYY-MM-DD"},
+				{Name: "limit", Description: "Page size, 100 by default and at most 1000", Format: "int32"},
+				{Name: "offset", Description: "Number of entries to skip", Format: "int32"},
+			},
+			Response: ActionLogListResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+			Handler:  h.getActionLogs,
+		},
+	}, h.subscriptionRoutes()...)
+}
+
+func (h *Handler) getRateV1(c *gin.Context) {
+	op := "http.v1.getRateV1"
+
+	format, err := negotiateFormat(c)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	exchanges, ok := h.latestRates(c, op)
+	if !ok {
+		return
+	}
+
+	rates := newRateDTOs(exchanges, pricingProfile(c))
+	if writeCacheHeaders(c, rateETag(format, rates), lastFetched(exchanges), cacheControlToday) {
+		return
+	}
+
+	writeRates(c, format, LatestRatesDTO{
+		Base:  exchanges[0].BaseCurrency.Code,
+		Rates: rates,
+	}, rates)
+}
+
+func (h *Handler) getRateMatrixV1(c *gin.Context) {
+	op := "http.v1.getRateMatrixV1"
+	date := c.Query("date")
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	h.logAction(ctx, op, internal.ActionLogRateMatrix, apiKeyString, map[string]any{"date": date})
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	matrix, err := h.server.exchangeRepository.GetMatrix(ctx, date)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RateMatrixDTO{
+		Date:       matrix.Date.Format("2006-01-02"),
+		Currencies: matrix.Currencies,
+		Rates:      matrix.Rates,
+	})
+}
+
+func (h *Handler) getRatesByDateV1(c *gin.Context) {
+	op := "http.v1.getRatesByDateV1"
+
+	format, err := negotiateFormat(c)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	ratesOnDate, ok := h.ratesByDate(c, op)
+	if !ok {
+		return
+	}
+
+	exchanges := ratesOnDate.Exchanges
+	rates := newRateDTOs(exchanges, pricingProfile(c))
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Base != rates[j].Base {
+			return rates[i].Base < rates[j].Base
+		}
+		return rates[i].Target < rates[j].Target
+	})
+
+	if writeCacheHeaders(c, rateETag(format, rates), lastFetched(exchanges), cacheControlForDate(h.server.clock, ratesOnDate.Date)) {
+		return
+	}
+
+	writeRates(c, format, RatesByDateDTO{
+		Date:          c.Query("date"),
+		EffectiveDate: ratesOnDate.EffectiveDate.Format("2006-01-02"),
+		Rates:         rates,
+	}, rates)
+}
+
+func (h *Handler) getTimeSeriesV1(c *gin.Context) {
+	op := "http.v1.getTimeSeriesV1"
+	base := c.Query("base")
+	symbols := querySymbols(c)
+	start := c.Query("start")
+	end := c.Query("end")
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	format, err := negotiateFormat(c)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	h.logAction(ctx, op, internal.ActionLogRateTimeSeries, apiKeyString,
+		map[string]any{"base": base, "symbols": symbols, "start": start, "end": end})
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	if err := requireQuery(c, "base", "start", "end"); err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	exchanges, err := h.server.exchangeRepository.GetTimeSeries(ctx, base, symbols, start, end)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	rates := newRateDTOs(exchanges, pricingProfile(c))
+	cacheControl := cacheControlRange
+	if endDate, _ := internal.ParseDate(end); cacheControlForDate(h.server.clock, endDate) == cacheControlToday {
+		cacheControl = cacheControlToday
+	}
+	if writeCacheHeaders(c, rateETag(format, rates), lastFetched(exchanges), cacheControl) {
+		return
+	}
+
+	writeRates(c, format, TimeSeriesDTO{
+		Base:  strings.ToUpper(base),
+		Start: start,
+		End:   end,
+		Rates: rates,
+	}, rates)
+}
+
+func (h *Handler) getFluctuationV1(c *gin.Context) {
+	op := "http.v1.getFluctuationV1"
+	base := c.Query("base")
+	symbols := querySymbols(c)
+	start := c.Query("start")
+	end := c.Query("end")
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	h.logAction(ctx, op, internal.ActionLogRateFluctuation, apiKeyString,
+		map[string]any{"base": base, "symbols": symbols, "start": start, "end": end})
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	if err := requireQuery(c, "base", "start", "end"); err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	fluctuations, err := h.server.exchangeRepository.GetFluctuation(ctx, base, symbols, start, end)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	rates := make([]FluctuationDTO, 0, len(fluctuations))
+	for _, fluctuation := range fluctuations {
+		rates = append(rates, FluctuationDTO{
+			Target:        fluctuation.TargetCurrency.Code,
+			StartDate:     fluctuation.StartDate.Format("2006-01-02"),
+			EndDate:       fluctuation.EndDate.Format("2006-01-02"),
+			StartRate:     fluctuation.StartRate,
+			EndRate:       fluctuation.EndRate,
+			Change:        fluctuation.Change,
+			ChangePercent: fluctuation.ChangePercent,
+		})
+	}
+
+	c.JSON(http.StatusOK, FluctuationListDTO{
+		Base:  strings.ToUpper(base),
+		Start: start,
+		End:   end,
+		Rates: rates,
+	})
+}
+
+func (h *Handler) convertV1(c *gin.Context) {
+	op := "http.v1.convertV1"
+	from := c.Query("from")
+	to := c.Query("to")
+	amountString := c.Query("amount")
+	date := c.Query("date")
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	h.logAction(ctx, op, internal.ActionLogRateConvert, apiKeyString,
+		map[string]any{"from": from, "to": to, "amount": amountString, "date": date})
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	if err := requireQuery(c, "from", "to", "amount"); err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	amount, err := strconv.ParseFloat(amountString, 64)
+	if err != nil {
+		writeError(c, op, internal.ErrInvalidAmount.With(map[string]any{"amount": amountString}))
+		return
+	}
+
+	conversion, err := h.server.exchangeRepository.Convert(ctx, from, to, amount, date)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	priced := pricingProfile(c).Convert(conversion)
+
+	c.JSON(http.StatusOK, ConversionDTO{
+		From:      priced.From.Code,
+		To:        priced.To.Code,
+		Amount:    priced.Amount,
+		Date:      priced.Date.Format("2006-01-02"),
+		Rate:      priced.Rate,
+		Bid:       priced.Quote.Bid,
+		Ask:       priced.Quote.Ask,
+		Mid:       priced.Quote.Mid,
+		Result:    priced.Result,
+		BidResult: priced.BidResult,
+		AskResult: priced.AskResult,
+	})
+}
+
+func (h *Handler) getRateStatsV1(c *gin.Context) {
+	op := "http.v1.getRateStatsV1"
+	base := c.Query("base")
+	symbol := c.Query("symbol")
+	start := c.Query("start")
+	end := c.Query("end")
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	h.logAction(ctx, op, internal.ActionLogRateStats, apiKeyString,
+		map[string]any{"base": base, "symbol": symbol, "start": start, "end": end})
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	if err := requireQuery(c, "base", "symbol", "start", "end"); err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	stats, err := h.server.exchangeRepository.GetStats(ctx, base, symbol, start, end)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RateStatsDTO{
+		Base:          stats.BaseCurrency.Code,
+		Target:        stats.TargetCurrency.Code,
+		Start:         stats.Start.Format("2006-01-02"),
+		End:           stats.End.Format("2006-01-02"),
+		Count:         stats.Count,
+		Min:           stats.Min,
+		Max:           stats.Max,
+		Mean:          stats.Mean,
+		Median:        stats.Median,
+		StdDev:        stats.StdDev,
+		First:         stats.First,
+		Last:          stats.Last,
+		ChangePercent: stats.ChangePercent,
+	})
+}
+
+func (h *Handler) getCandlesV1(c *gin.Context) {
+	op := "http.v1.getCandlesV1"
+	base := c.Query("base")
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	start := c.Query("start")
+	end := c.Query("end")
+	apiKeyString := c.Query("apikey")
+	ctx := c.Request.Context()
+
+	h.logAction(ctx, op, internal.ActionLogRateOHLC, apiKeyString,
+		map[string]any{"base": base, "symbol": symbol, "interval": interval, "start": start, "end": end})
+
+	if !h.authorize(c, op, apiKeyString) {
+		return
+	}
+
+	if err := requireQuery(c, "base", "symbol", "start", "end"); err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	candles, err := h.server.exchangeRepository.GetCandles(ctx, base, symbol, interval, start, end)
+	if err != nil {
+		writeError(c, op, err)
+		return
+	}
+
+	// Интервал уже проверен репозиторием, здесь он только приводится к
+	// каноническому виду.
+	candleInterval, _ := internal.ParseCandleInterval(interval)
+
+	response := CandleListDTO{
+		Base:     strings.ToUpper(base),
+		Target:   strings.ToUpper(symbol),
+		Interval: string(candleInterval),
+		Candles:  make([]CandleDTO, 0, len(candles)),
+	}
+
+	for _, candle := range candles {
+		response.Candles = append(response.Candles, CandleDTO{
+			Start: candle.Start,
+			End:   candle.End,
+			Open:  candle.Open,
+			High:  candle.High,
+			Low:   candle.Low,
+			Close: candle.Close,
+			Count: candle.Count,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func newRateDTOs(exchanges []internal.Exchange, pricing *internal.PricingProfile) []RateDTO {
+	rates := make([]RateDTO, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		rates = append(rates, newRateDTO(exchange, pricing))
+	}
+
+	return rates
+}
+
+func newRateDTO(exchange internal.Exchange, pricing *internal.PricingProfile) RateDTO {
+	quote := pricing.Quote(exchange.BaseCurrency.Code, exchange.TargetCurrency.Code, exchange.Rate)
+
+	return RateDTO{
+		Base:   exchange.BaseCurrency.Code,
+		Target: exchange.TargetCurrency.Code,
+		Rate:   quote.Mid,
+		Bid:    quote.Bid,
+		Ask:    quote.Ask,
+		Mid:    quote.Mid,
+		Date:   exchange.Timestamp.Format("2006-01-02"),
+	}
+}
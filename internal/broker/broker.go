@@ -0,0 +1,146 @@
+package broker
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrClosed       = errors.New("broker closed")
+	ErrSlowConsumer = errors.New("subscriber is too slow")
+)
+
+// Broker рассылает сообщения подписчикам по темам в памяти процесса.
+// Publish никогда не блокируется: если буфер подписчика заполнен, подписка
+// закрывается с ErrSlowConsumer, чтобы медленный клиент не тормозил
+// остальных.
+type Broker[T any] struct {
+	bufferSize int
+
+	mu          sync.RWMutex
+	subscribers map[*Subscription[T]]struct{}
+	closed      bool
+
+	dropped atomic.Uint64
+}
+
+type Subscription[T any] struct {
+	broker *Broker[T]
+	topics map[string]struct{}
+	ch     chan T
+
+	once sync.Once
+	err  error
+}
+
+func New[T any](bufferSize int) *Broker[T] {
+	return &Broker[T]{
+		bufferSize:  bufferSize,
+		subscribers: map[*Subscription[T]]struct{}{},
+	}
+}
+
+// Subscribe подписывает на темы topics, пустой список означает все темы.
+func (b *Broker[T]) Subscribe(topics []string) (*Subscription[T], error) {
+	sub := &Subscription[T]{
+		broker: b,
+		ch:     make(chan T, b.bufferSize),
+	}
+
+	if len(topics) > 0 {
+		sub.topics = make(map[string]struct{}, len(topics))
+		for _, topic := range topics {
+			sub.topics[topic] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	b.subscribers[sub] = struct{}{}
+
+	return sub, nil
+}
+
+func (b *Broker[T]) Publish(topic string, message T) {
+	b.mu.RLock()
+	var slow []*Subscription[T]
+	for sub := range b.subscribers {
+		if sub.topics != nil {
+			if _, ok := sub.topics[topic]; !ok {
+				continue
+			}
+		}
+
+		select {
+		case sub.ch <- message:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range slow {
+		b.dropped.Add(1)
+		sub.close(ErrSlowConsumer)
+	}
+}
+
+// Close закрывает все подписки с ErrClosed и больше не принимает новых.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subscribers := make([]*Subscription[T], 0, len(b.subscribers))
+	for sub := range b.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.close(ErrClosed)
+	}
+}
+
+func (b *Broker[T]) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscribers)
+}
+
+// Dropped - количество подписок, закрытых из-за переполнения буфера.
+func (b *Broker[T]) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// C возвращает канал сообщений. Канал закрывается, когда подписка
+// завершена; причину возвращает Err.
+func (s *Subscription[T]) C() <-chan T {
+	return s.ch
+}
+
+func (s *Subscription[T]) Err() error {
+	s.broker.mu.RLock()
+	defer s.broker.mu.RUnlock()
+
+	return s.err
+}
+
+func (s *Subscription[T]) Unsubscribe() {
+	s.close(nil)
+}
+
+func (s *Subscription[T]) close(err error) {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subscribers, s)
+		s.err = err
+		close(s.ch)
+		s.broker.mu.Unlock()
+	})
+}
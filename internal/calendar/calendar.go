@@ -0,0 +1,225 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLookback ограничивает поиск предыдущего рабочего дня, чтобы ошибка в
+// настройках не превращала его в бесконечный цикл.
+const maxLookback = 31
+
+var defaultWeekend = []time.Weekday{time.Saturday, time.Sunday}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Config - описание рынков в файле календаря.
+type Config struct {
+	Markets map[string]MarketConfig `json:"markets"`
+}
+
+// MarketConfig - валюты рынка, его выходные дни недели и праздники. Праздник
+// задаётся датой "2006-01-02" или ежегодной датой "01-02". Без weekend
+// выходными считаются суббота и воскресенье.
+type MarketConfig struct {
+	Currencies []string `json:"currencies"`
+	Weekend    []string `json:"weekend,omitempty"`
+	Holidays   []string `json:"holidays"`
+}
+
+type market struct {
+	name     string
+	weekend  map[time.Weekday]struct{}
+	dates    map[string]struct{}
+	annually map[string]struct{}
+}
+
+// Calendar определяет рабочие дни валют. Валюта без рынка работает по
+// будням без праздников.
+type Calendar struct {
+	markets map[string][]*market
+	weekend map[time.Weekday]struct{}
+}
+
+func New(config Config) (*Calendar, error) {
+	op := "calendar.calendar.New"
+
+	calendar := &Calendar{
+		markets: make(map[string][]*market),
+		weekend: weekendSet(defaultWeekend),
+	}
+
+	for name, marketConfig := range config.Markets {
+		m := &market{
+			name:     name,
+			weekend:  weekendSet(defaultWeekend),
+			dates:    make(map[string]struct{}),
+			annually: make(map[string]struct{}),
+		}
+
+		if len(marketConfig.Weekend) > 0 {
+			m.weekend = make(map[time.Weekday]struct{}, len(marketConfig.Weekend))
+			for _, day := range marketConfig.Weekend {
+				weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]
+				if !ok {
+					return nil, fmt.Errorf("%s: market %s: unknown weekday %q", op, name, day)
+				}
+				m.weekend[weekday] = struct{}{}
+			}
+		}
+
+		for _, holiday := range marketConfig.Holidays {
+			holiday = strings.TrimSpace(holiday)
+			if _, err := time.Parse(time.DateOnly, holiday); err == nil {
+				m.dates[holiday] = struct{}{}
+				continue
+			}
+			// 2000 - високосный год, поэтому "02-29" тоже разбирается
+			if _, err := time.Parse(time.DateOnly, "2000-"+holiday); err == nil {
+				m.annually[holiday] = struct{}{}
+				continue
+			}
+
+			return nil, fmt.Errorf("%s: market %s: invalid holiday %q", op, name, holiday)
+		}
+
+		for _, currency := range marketConfig.Currencies {
+			code := strings.ToUpper(strings.TrimSpace(currency))
+			calendar.markets[code] = append(calendar.markets[code], m)
+		}
+	}
+
+	return calendar, nil
+}
+
+// Load читает календарь из JSON файла. Пустой путь означает календарь без
+// праздников.
+func Load(path string) (*Calendar, error) {
+	op := "calendar.calendar.Load"
+
+	if path == "" {
+		return New(Config{})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var config Config
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", op, path, err)
+	}
+
+	calendar, err := New(config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return calendar, nil
+}
+
+// IsBusinessDay сообщает, рабочий ли date для всех currencies. Время суток
+// и часовой пояс не учитываются, берётся только календарная дата.
+func (c *Calendar) IsBusinessDay(date time.Time, currencies ...string) bool {
+	for _, currency := range currencies {
+		markets, ok := c.markets[strings.ToUpper(currency)]
+		if !ok {
+			if _, weekend := c.weekend[date.Weekday()]; weekend {
+				return false
+			}
+			continue
+		}
+
+		for _, m := range markets {
+			if m.closed(date) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// PreviousBusinessDay возвращает последний рабочий для всех currencies день,
+// не позже date. Если такого дня нет в пределах месяца, возвращается date.
+func (c *Calendar) PreviousBusinessDay(date time.Time, currencies ...string) time.Time {
+	for day := 0; day <= maxLookback; day++ {
+		candidate := date.AddDate(0, 0, -day)
+		if c.IsBusinessDay(candidate, currencies...) {
+			return candidate
+		}
+	}
+
+	return date
+}
+
+// MissingYear возвращает рынки, у которых есть праздники на конкретные даты,
+// но нет ни одного в year. Переносимые праздники задаются только датами,
+// поэтому у таких рынков календарь на year, скорее всего, не заполнен.
+func (c *Calendar) MissingYear(year int) []string {
+	prefix := strconv.Itoa(year) + "-"
+	seen := make(map[*market]struct{})
+
+	var missing []string
+	for _, markets := range c.markets {
+		for _, m := range markets {
+			if _, ok := seen[m]; ok {
+				continue
+			}
+			seen[m] = struct{}{}
+
+			if len(m.dates) > 0 && !m.hasDateWithPrefix(prefix) {
+				missing = append(missing, m.name)
+			}
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+func (m *market) hasDateWithPrefix(prefix string) bool {
+	for date := range m.dates {
+		if strings.HasPrefix(date, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *market) closed(date time.Time) bool {
+	if _, ok := m.weekend[date.Weekday()]; ok {
+		return true
+	}
+	if _, ok := m.dates[date.Format(time.DateOnly)]; ok {
+		return true
+	}
+	_, ok := m.annually[date.Format("01-02")]
+
+	return ok
+}
+
+func weekendSet(days []time.Weekday) map[time.Weekday]struct{} {
+	set := make(map[time.Weekday]struct{}, len(days))
+	for _, day := range days {
+		set[day] = struct{}{}
+	}
+
+	return set
+}
@@ -0,0 +1,171 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed
+}
+
+func testCalendar(t *testing.T) *Calendar {
+	t.Helper()
+
+	calendar, err := New(Config{Markets: map[string]MarketConfig{
+		"moscow": {Currencies: []string{"rub"}, Holidays: []string{"2024-03-08", "01-01"}},
+		"dubai":  {Currencies: []string{"AED"}, Weekend: []string{"Friday", "saturday"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return calendar
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	calendar := testCalendar(t)
+
+	tests := []struct {
+		name       string
+		date       string
+		currencies []string
+		want       bool
+	}{
+		{"weekday without market", "2024-03-07", []string{"USD"}, true},
+		{"saturday without market", "2024-03-09", []string{"USD"}, false},
+		{"dated holiday", "2024-03-08", []string{"RUB"}, false},
+		{"dated holiday of one currency in pair", "2024-03-08", []string{"USD", "RUB"}, false},
+		{"dated holiday of other market", "2024-03-08", []string{"USD", "EUR"}, true},
+		{"dated holiday only in its year", "2023-03-08", []string{"RUB"}, true},
+		{"annual holiday", "2025-01-01", []string{"RUB"}, false},
+		{"annual holiday next year", "2026-01-01", []string{"rub"}, false},
+		{"custom weekend", "2024-03-08", []string{"AED"}, false},
+		{"sunday is business day on custom weekend", "2024-03-10", []string{"AED"}, true},
+		{"custom weekend and default weekend", "2024-03-10", []string{"AED", "USD"}, false},
+		{"no currencies", "2024-03-09", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.IsBusinessDay(date(t, tt.date), tt.currencies...); got != tt.want {
+				t.Errorf("IsBusinessDay(%s, %v) = %v, want %v", tt.date, tt.currencies, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousBusinessDay(t *testing.T) {
+	calendar := testCalendar(t)
+
+	tests := []struct {
+		name       string
+		date       string
+		currencies []string
+		want       string
+	}{
+		{"business day", "2024-03-07", []string{"USD", "RUB"}, "2024-03-07"},
+		{"sunday", "2024-03-10", []string{"USD"}, "2024-03-08"},
+		{"holiday before weekend", "2024-03-10", []string{"USD", "RUB"}, "2024-03-07"},
+		{"holiday", "2024-03-08", []string{"RUB"}, "2024-03-07"},
+		{"annual holiday after weekend", "2024-01-01", []string{"RUB"}, "2023-12-29"},
+		{"custom weekend", "2024-03-09", []string{"AED"}, "2024-03-07"},
+		{"weekends of both markets", "2024-03-10", []string{"AED", "USD"}, "2024-03-07"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calendar.PreviousBusinessDay(date(t, tt.date), tt.currencies...).Format(time.DateOnly)
+			if got != tt.want {
+				t.Errorf("PreviousBusinessDay(%s, %v) = %s, want %s", tt.date, tt.currencies, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousBusinessDayWithoutBusinessDays(t *testing.T) {
+	calendar, err := New(Config{Markets: map[string]MarketConfig{
+		"closed": {
+			Currencies: []string{"XXX"},
+			Weekend:    []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := date(t, "2024-03-07")
+	if got := calendar.PreviousBusinessDay(day, "XXX"); !got.Equal(day) {
+		t.Errorf("PreviousBusinessDay() = %s, want %s", got.Format(time.DateOnly), day.Format(time.DateOnly))
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		market MarketConfig
+	}{
+		{"unknown weekday", MarketConfig{Currencies: []string{"RUB"}, Weekend: []string{"funday"}}},
+		{"invalid holiday", MarketConfig{Currencies: []string{"RUB"}, Holidays: []string{"2024-02-30"}}},
+		{"invalid annual holiday", MarketConfig{Currencies: []string{"RUB"}, Holidays: []string{"13-01"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{Markets: map[string]MarketConfig{"market": tt.market}})
+			if err == nil {
+				t.Errorf("New() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestMissingYear(t *testing.T) {
+	calendar := testCalendar(t)
+
+	if missing := calendar.MissingYear(2024); len(missing) != 0 {
+		t.Errorf("MissingYear(2024) = %v, want none", missing)
+	}
+	// У dubai нет праздников на конкретные даты, у moscow - только ежегодные в 2025
+	if missing := calendar.MissingYear(2025); !slices.Equal(missing, []string{"moscow"}) {
+		t.Errorf("MissingYear(2025) = %v, want [moscow]", missing)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	err := os.WriteFile(path, []byte(`{"markets":{"tokyo":{"currencies":["JPY"],"holidays":["2024-03-20"]}}}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calendar.IsBusinessDay(date(t, "2024-03-20"), "JPY") {
+		t.Errorf("IsBusinessDay(2024-03-20, JPY) = true, want false")
+	}
+
+	empty, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !empty.IsBusinessDay(date(t, "2024-03-20"), "JPY") {
+		t.Errorf("IsBusinessDay(2024-03-20, JPY) without file = false, want true")
+	}
+}
+
+func TestLoadRepositoryCalendar(t *testing.T) {
+	_, err := Load(filepath.Join("..", "..", "calendar.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Business - часы сервиса. Календарные даты считаются в часовом поясе
+// location, а курсы за день считаются опубликованными с момента cutoff после
+// его начала; до этого текущими остаются курсы предыдущего дня.
+type Business struct {
+	location *time.Location
+	cutoff   time.Duration
+	now      func() time.Time
+}
+
+func NewBusiness(location *time.Location, cutoff time.Duration) *Business {
+	if location == nil {
+		location = time.UTC
+	}
+
+	return &Business{location: location, cutoff: cutoff, now: time.Now}
+}
+
+func (c *Business) Now() time.Time {
+	return c.now().In(c.location)
+}
+
+func (c *Business) Location() *time.Location {
+	return c.location
+}
+
+// Date возвращает календарную дату момента t в часовом поясе часов как
+// полночь UTC - в том виде, в котором даты курсов хранятся и сравниваются.
+func (c *Business) Date(t time.Time) time.Time {
+	local := t.In(c.location)
+
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RateDate возвращает дату курсов, которые были текущими в момент t. Время
+// публикации сравнивается с местным временем на часах, а не с временем от
+// полуночи, поэтому в дни перевода часов оно не сдвигается.
+func (c *Business) RateDate(t time.Time) time.Time {
+	local := t.In(c.location)
+	wall := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+
+	date := c.Date(t)
+	if wall < c.cutoff {
+		return date.AddDate(0, 0, -1)
+	}
+
+	return date
+}
+
+// ParseCutoff разбирает время публикации курсов в формате "15:04". Пустая
+// строка означает полночь.
+func ParseCutoff(value string) (time.Duration, error) {
+	op := "clock.clock.ParseCutoff"
+
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid cutoff %q: %w", op, value, err)
+	}
+
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// Fake - часы для тестов: время не идёт само, а задаётся через Set и
+// Advance. Даты считаются так же, как в Business.
+type Fake struct {
+	*Business
+
+	mu      sync.Mutex
+	current time.Time
+}
+
+func NewFake(now time.Time, location *time.Location, cutoff time.Duration) *Fake {
+	fake := &Fake{Business: NewBusiness(location, cutoff), current: now}
+	fake.Business.now = fake.get
+
+	return fake
+}
+
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.current = now
+}
+
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.current = f.current.Add(d)
+}
+
+func (f *Fake) get() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.current
+}
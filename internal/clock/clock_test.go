@@ -0,0 +1,114 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCutoff(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"00:00", 0, false},
+		{"16:00", 16 * time.Hour, false},
+		{"09:30", 9*time.Hour + 30*time.Minute, false},
+		{"23:59", 23*time.Hour + 59*time.Minute, false},
+		{"24:00", 0, true},
+		{"16", 0, true},
+		{"4pm", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCutoff(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCutoff(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCutoff(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRateDate(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		location *time.Location
+		cutoff   time.Duration
+		date     string
+		rateDate string
+	}{
+		{"utc midnight cutoff", time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), time.UTC, 0, "2024-03-04", "2024-03-04"},
+		{"before cutoff", time.Date(2024, 3, 4, 15, 59, 0, 0, time.UTC), time.UTC, 16 * time.Hour, "2024-03-04", "2024-03-03"},
+		{"at cutoff", time.Date(2024, 3, 4, 16, 0, 0, 0, time.UTC), time.UTC, 16 * time.Hour, "2024-03-04", "2024-03-04"},
+		// 22:30 UTC - уже 01:30 следующего дня по Москве
+		{"next day in location", time.Date(2024, 3, 4, 22, 30, 0, 0, time.UTC), moscow, 0, "2024-03-05", "2024-03-05"},
+		{"next day before cutoff", time.Date(2024, 3, 4, 22, 30, 0, 0, time.UTC), moscow, 12 * time.Hour, "2024-03-05", "2024-03-04"},
+		{"first of month before cutoff", time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC), time.UTC, 12 * time.Hour, "2024-03-01", "2024-02-29"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFake(tt.now, tt.location, tt.cutoff)
+
+			if got := fake.Date(fake.Now()).Format(time.DateOnly); got != tt.date {
+				t.Errorf("Date() = %s, want %s", got, tt.date)
+			}
+			if got := fake.RateDate(fake.Now()).Format(time.DateOnly); got != tt.rateDate {
+				t.Errorf("RateDate() = %s, want %s", got, tt.rateDate)
+			}
+		})
+	}
+}
+
+func TestRateDateAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name     string
+		local    time.Time
+		rateDate string
+	}{
+		// 2024-03-10 часы переводятся с 02:00 на 03:00, в сутках 23 часа
+		{"spring forward before cutoff", time.Date(2024, 3, 10, 15, 59, 0, 0, newYork), "2024-03-09"},
+		{"spring forward at cutoff", time.Date(2024, 3, 10, 16, 0, 0, 0, newYork), "2024-03-10"},
+		// 2024-11-03 часы переводятся с 02:00 на 01:00, в сутках 25 часов
+		{"fall back before cutoff", time.Date(2024, 11, 3, 15, 59, 0, 0, newYork), "2024-11-02"},
+		{"fall back at cutoff", time.Date(2024, 11, 3, 16, 0, 0, 0, newYork), "2024-11-03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFake(tt.local.UTC(), newYork, 16*time.Hour)
+			if got := fake.RateDate(fake.Now()).Format(time.DateOnly); got != tt.rateDate {
+				t.Errorf("RateDate(%s) = %s, want %s", tt.local, got, tt.rateDate)
+			}
+		})
+	}
+}
+
+func TestFakeAdvance(t *testing.T) {
+	fake := NewFake(time.Date(2024, 3, 4, 11, 0, 0, 0, time.UTC), time.UTC, 12*time.Hour)
+	if got := fake.RateDate(fake.Now()).Format(time.DateOnly); got != "2024-03-03" {
+		t.Fatalf("RateDate() = %s, want 2024-03-03", got)
+	}
+
+	fake.Advance(time.Hour)
+	if got := fake.RateDate(fake.Now()).Format(time.DateOnly); got != "2024-03-04" {
+		t.Errorf("RateDate() after Advance = %s, want 2024-03-04", got)
+	}
+
+	fake.Set(time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC))
+	if got := fake.RateDate(fake.Now()).Format(time.DateOnly); got != "2024-03-08" {
+		t.Errorf("RateDate() after Set = %s, want 2024-03-08", got)
+	}
+}
@@ -0,0 +1,204 @@
+package filearchive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+const archiveExtension string = ".jsonl.gz"
+
+// maxArchiveSuffix ограничивает поиск свободного имени архива.
+const maxArchiveSuffix int = 1000
+
+type ActionLogArchiver struct {
+	dir string
+}
+
+type actionLogRecord struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	APIKey    string         `json:"api_key,omitempty"`
+	Payload   map[string]any `json:"payload"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+func NewActionLogArchiver(dir string) *ActionLogArchiver {
+	return &ActionLogArchiver{dir: dir}
+}
+
+func (aa *ActionLogArchiver) Create(ctx context.Context, name string) (internal.ActionLogArchiveWriter, error) {
+	op := "fileArchive.actionlog.Create"
+
+	err := os.MkdirAll(aa.dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	file, err := os.CreateTemp(aa.dir, name+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	gzipWriter := gzip.NewWriter(file)
+	writer := &actionLogWriter{
+		file:    file,
+		gzip:    gzipWriter,
+		encoder: json.NewEncoder(gzipWriter),
+		path:    filepath.Join(aa.dir, name+archiveExtension),
+	}
+
+	return writer, nil
+}
+
+// actionLogWriter пишет во временный файл и переносит его под итоговое имя
+// только в Commit, поэтому в каталоге архива не бывает недописанных файлов.
+// Существующий архив не перезаписывается, а пустой архив не создаётся. Если
+// под этим именем уже лежит такой же архив (партиция была выгружена, но не
+// удалена из-за остановки сервиса), выгрузка считается выполненной, а
+// другой архив сохраняется под именем с номером.
+type actionLogWriter struct {
+	file    *os.File
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+	path    string
+	records int
+}
+
+func (aw *actionLogWriter) Write(actionLog internal.ActionLog) error {
+	op := "fileArchive.actionlog.Write"
+
+	record := actionLogRecord{
+		ID:        string(actionLog.ID),
+		Type:      actionLog.ActionLogType.Action,
+		APIKey:    actionLog.APIKey,
+		Payload:   actionLog.Payload,
+		Timestamp: actionLog.Timestamp,
+	}
+
+	err := aw.encoder.Encode(record)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	aw.records++
+	return nil
+}
+
+func (aw *actionLogWriter) Commit() error {
+	op := "fileArchive.actionlog.Commit"
+
+	if aw.records == 0 {
+		return aw.Abort()
+	}
+
+	err := aw.gzip.Close()
+	if err != nil {
+		aw.Abort()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = aw.file.Sync()
+	if err != nil {
+		aw.Abort()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = aw.file.Close()
+	if err != nil {
+		os.Remove(aw.file.Name())
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer os.Remove(aw.file.Name())
+
+	path := aw.path
+	for suffix := 1; ; suffix++ {
+		// Link, в отличие от Rename, не заменяет уже существующий файл.
+		err = os.Link(aw.file.Name(), path)
+		if err == nil {
+			return nil
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		same, err := sameContent(aw.file.Name(), path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if same {
+			return nil
+		}
+
+		if suffix > maxArchiveSuffix {
+			return fmt.Errorf("%s: no free archive name for %s", op, aw.path)
+		}
+		path = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(aw.path, archiveExtension), suffix, archiveExtension)
+	}
+}
+
+// sameContent сравнивает файлы по размеру и контрольной сумме.
+func sameContent(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	sumA, err := fileChecksum(a)
+	if err != nil {
+		return false, err
+	}
+	sumB, err := fileChecksum(b)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(sumA, sumB), nil
+}
+
+func fileChecksum(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+func (aw *actionLogWriter) Abort() error {
+	op := "fileArchive.actionlog.Abort"
+
+	aw.file.Close()
+
+	err := os.Remove(aw.file.Name())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package filearchive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+func archivePartition(t *testing.T, archiver *ActionLogArchiver, name string, payloads ...map[string]any) error {
+	t.Helper()
+
+	writer, err := archiver.Create(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, payload := range payloads {
+		actionLog, err := internal.NewActionLog("rate.pair", "key", payload, time.Date(2025, time.July, 1, i, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Write(actionLog); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return writer.Commit()
+}
+
+func archiveFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestCommitSameArchiveAgain(t *testing.T) {
+	dir := t.TempDir()
+	archiver := NewActionLogArchiver(dir)
+	payload := map[string]any{"base": "USD"}
+
+	if err := archivePartition(t, archiver, "exchange_rates_log_20250701", payload); err != nil {
+		t.Fatal(err)
+	}
+	// Повторная выгрузка той же партиции после остановки до её удаления
+	if err := archivePartition(t, archiver, "exchange_rates_log_20250701", payload); err != nil {
+		t.Fatalf("second Commit() error = %v", err)
+	}
+
+	got := archiveFiles(t, dir)
+	want := []string{"exchange_rates_log_20250701.jsonl.gz"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("archive files = %v, want %v", got, want)
+	}
+}
+
+func TestCommitDifferentArchiveWithSameName(t *testing.T) {
+	dir := t.TempDir()
+	archiver := NewActionLogArchiver(dir)
+
+	if err := archivePartition(t, archiver, "exchange_rates_log_20250701", map[string]any{"base": "USD"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := archivePartition(t, archiver, "exchange_rates_log_20250701", map[string]any{"base": "EUR"}); err != nil {
+		t.Fatalf("second Commit() error = %v", err)
+	}
+	if err := archivePartition(t, archiver, "exchange_rates_log_20250701", map[string]any{"base": "RUB"}); err != nil {
+		t.Fatalf("third Commit() error = %v", err)
+	}
+
+	got := archiveFiles(t, dir)
+	want := []string{
+		"exchange_rates_log_20250701.1.jsonl.gz",
+		"exchange_rates_log_20250701.2.jsonl.gz",
+		"exchange_rates_log_20250701.jsonl.gz",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("archive files = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("archive files = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestCommitEmptyArchive(t *testing.T) {
+	dir := t.TempDir()
+	archiver := NewActionLogArchiver(dir)
+
+	if err := archivePartition(t, archiver, "exchange_rates_log_20250701"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := archiveFiles(t, dir); len(got) != 0 {
+		t.Errorf("archive files = %v, want none", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "exchange_rates_log_20250701.jsonl.gz")); !os.IsNotExist(err) {
+		t.Errorf("empty archive was created")
+	}
+}
@@ -0,0 +1,103 @@
+package freecurrencyapi
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	CircuitClosed   string = "closed"
+	CircuitOpen     string = "open"
+	CircuitHalfOpen string = "half_open"
+)
+
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+const (
+	circuitFailureThreshold int           = 5
+	circuitCooldown         time.Duration = 30 * time.Second
+)
+
+// circuitBreaker перестаёт пропускать запросы к апи после серии ошибок подряд.
+// Через circuitCooldown пропускается один пробный запрос: успех закрывает
+// цепь, ошибка снова открывает её. Пробный запрос, завершившийся без ответа
+// апи, освобождается через release.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		state:     CircuitClosed,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow пропускает запрос или возвращает errCircuitOpen. probe - запрос
+// пробный, после него вызывающий обязан вызвать release.
+func (cb *circuitBreaker) allow() (probe bool, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return false, errCircuitOpen
+		}
+		cb.state = CircuitHalfOpen
+		return true, nil
+	case CircuitHalfOpen:
+		return false, errCircuitOpen
+	default:
+		return false, nil
+	}
+}
+
+// release завершает пробный запрос. Если он не вызвал ни success, ни failure
+// (контекст отменён или запрос не был отправлен), цепь возвращается в open с
+// прежним временем открытия, и пробным станет следующий запрос.
+func (cb *circuitBreaker) release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitHalfOpen {
+		cb.state = CircuitOpen
+	}
+}
+
+func (cb *circuitBreaker) success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.state = CircuitClosed
+	cb.failures = 0
+}
+
+func (cb *circuitBreaker) failure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.threshold {
+		cb.state = CircuitOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+func (cb *circuitBreaker) State() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.cooldown {
+		return CircuitHalfOpen
+	}
+
+	return cb.state
+}
@@ -0,0 +1,102 @@
+package freecurrencyapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndProbes(t *testing.T) {
+	cb := newCircuitBreaker(2, time.Hour)
+
+	cb.failure()
+	if state := cb.State(); state != CircuitClosed {
+		t.Fatalf("State() = %s after one failure, want %s", state, CircuitClosed)
+	}
+
+	cb.failure()
+	if _, err := cb.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("allow() error = %v, want errCircuitOpen", err)
+	}
+
+	// Время открытия в прошлом - перерыв закончился
+	cb.openedAt = time.Now().Add(-2 * time.Hour)
+	probe, err := cb.allow()
+	if err != nil || !probe {
+		t.Fatalf("allow() = %v, %v, want probe", probe, err)
+	}
+	if _, err := cb.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("second allow() during probe error = %v, want errCircuitOpen", err)
+	}
+
+	cb.success()
+	cb.release()
+	if state := cb.State(); state != CircuitClosed {
+		t.Fatalf("State() = %s after successful probe, want %s", state, CircuitClosed)
+	}
+}
+
+func TestCircuitBreakerReleasesCancelledProbe(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"data":{"EUR":0.9}}`))
+	}))
+	defer server.Close()
+
+	api := &ExchangeExternalAPI{
+		client:  server.Client(),
+		circuit: newCircuitBreaker(1, time.Hour),
+	}
+	api.circuit.failure()
+	api.circuit.openedAt = time.Now().Add(-2 * time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := api.fetch(ctx, server.URL)
+	if err == nil {
+		t.Fatalf("fetch() with cancelled context error = nil")
+	}
+	if state := api.circuit.State(); state != CircuitHalfOpen {
+		t.Fatalf("State() = %s after cancelled probe, want %s", state, CircuitHalfOpen)
+	}
+
+	resp, err := api.fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("fetch() after cancelled probe error = %v", err)
+	}
+	if resp.Rates["EUR"] != 0.9 {
+		t.Errorf("Rates = %v", resp.Rates)
+	}
+	if state := api.circuit.State(); state != CircuitClosed {
+		t.Errorf("State() = %s, want %s", state, CircuitClosed)
+	}
+	if requests != 1 {
+		t.Errorf("server got %d requests, want 1", requests)
+	}
+}
+
+func TestCircuitBreakerFailedProbeReopens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	api := &ExchangeExternalAPI{
+		client:  server.Client(),
+		circuit: newCircuitBreaker(1, time.Hour),
+	}
+	api.circuit.failure()
+	api.circuit.openedAt = time.Now().Add(-2 * time.Hour)
+
+	_, err := api.fetch(context.Background(), server.URL)
+	if err == nil {
+		t.Fatalf("fetch() error = nil")
+	}
+	if state := api.circuit.State(); state != CircuitOpen {
+		t.Errorf("State() = %s after failed probe, want %s", state, CircuitOpen)
+	}
+}
@@ -1,23 +1,41 @@
 package freecurrencyapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/logger"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var baseURL string = "https://api.freecurrencyapi.com/v1/latest"
 
 type ExchangeExternalAPI struct {
-	APIKey string
+	APIKey  string
+	client  *http.Client
+	circuit *circuitBreaker
 }
 
+const providerName string = "freecurrencyapi"
 const baseTimeFormate string = "2006-01-02"
+const requestTimeout time.Duration = 10 * time.Second
+
+// EarliestDate - первая дата, за которую у freecurrencyapi есть исторические
+// курсы.
+var EarliestDate = time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC)
 
 type RateResponse struct {
 	Base  string
@@ -26,74 +44,172 @@ type RateResponse struct {
 
 func NewExchangeExternalAPI(APIKey string) *ExchangeExternalAPI {
 	return &ExchangeExternalAPI{
-		APIKey: APIKey,
+		APIKey:  APIKey,
+		client:  &http.Client{Timeout: requestTimeout},
+		circuit: newCircuitBreaker(circuitFailureThreshold, circuitCooldown),
 	}
 }
 
-func (fc *ExchangeExternalAPI) GetByBase(baseCurrencyCode, targetCurrencyCode string) (internal.Exchange, error) {
-	op := "FreeCurrencyAPI.exchange.GetByBase"
+func (fc *ExchangeExternalAPI) Name() string {
+	return providerName
+}
 
-	requestUrl := fmt.Sprintf("%s?&apikey=%s&base_currency=%s&currencies=%s", baseURL, fc.APIKey, baseCurrencyCode, targetCurrencyCode)
+func (fc *ExchangeExternalAPI) CircuitState() string {
+	return fc.circuit.State()
+}
 
-	resp, err := http.Get(requestUrl)
-	if err != nil {
-		return internal.Exchange{}, fmt.Errorf("%s: %s", op, err)
-	}
-	defer resp.Body.Close()
+// GetLatest запрашивает текущие курсы base ко всем targetCurrencyCodes
+// одним запросом.
+func (fc *ExchangeExternalAPI) GetLatest(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string) ([]internal.Exchange, error) {
+	op := "FreeCurrencyAPI.exchange.GetLatest"
+	ctx, span := tracing.Start(ctx, op,
+		tracing.ProviderKey.String(providerName),
+		tracing.BaseCurrencyKey.String(baseCurrencyCode),
+		tracing.TargetCurrenciesKey.StringSlice(targetCurrencyCodes))
+	defer span.End()
 
-	if resp.StatusCode != http.StatusOK {
-		err := fmt.Sprintf("%s: Не удалось получить данные со стороннего апи. Ответ: %s", op, resp.Status)
-		return internal.Exchange{}, fmt.Errorf("%s: %s", op, err)
+	requestUrl := fmt.Sprintf("%s?&apikey=%s&base_currency=%s&currencies=%s", baseURL, fc.APIKey, baseCurrencyCode, strings.Join(targetCurrencyCodes, ","))
+
+	apiResp, err := fc.fetch(ctx, requestUrl)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 	}
 
-	body, _ := io.ReadAll(resp.Body)
+	result := make([]internal.Exchange, 0, len(targetCurrencyCodes))
+	for _, targetCurrencyCode := range targetCurrencyCodes {
+		rate, ok := apiResp.Rates[targetCurrencyCode]
+		if !ok {
+			err := internal.ErrNotFound.With(map[string]any{"provider": providerName, "base": baseCurrencyCode, "target": targetCurrencyCode})
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
 
-	var apiResp RateResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return internal.Exchange{}, fmt.Errorf("%s: %s", op, err)
-	}
+		exchange, err := internal.NewExchange(baseCurrencyCode, targetCurrencyCode, rate, time.Now())
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+		exchange.FetchedAt = time.Now()
 
-	exchange, err := internal.NewExchange(baseCurrencyCode, targetCurrencyCode, apiResp.Rates[targetCurrencyCode], time.Now())
-	if err != nil {
-		return internal.Exchange{}, fmt.Errorf("%s: %s", op, err)
+		result = append(result, exchange)
 	}
 
-	return exchange, nil
+	return result, nil
 }
 
-func (fc *ExchangeExternalAPI) GetByDate(baseCurrencyCode string, targetCurrencyCode []string, date time.Time) ([]internal.Exchange, error) {
+func (fc *ExchangeExternalAPI) GetByDate(ctx context.Context, baseCurrencyCode string, targetCurrencyCode []string, date time.Time) ([]internal.Exchange, error) {
 	op := "FreeCurrencyAPI.exchange.GetByDate"
 	parsedDate := date.Format(baseTimeFormate)
+	ctx, span := tracing.Start(ctx, op,
+		tracing.ProviderKey.String(providerName),
+		tracing.BaseCurrencyKey.String(baseCurrencyCode),
+		tracing.TargetCurrenciesKey.StringSlice(targetCurrencyCode),
+		tracing.DateKey.String(parsedDate))
+	defer span.End()
 	result := make([]internal.Exchange, 0, 4)
 
 	requestUrl := fmt.Sprintf("%s?&apikey=%s&date=%s&base_currency=%s&currencies=%s", baseURL, fc.APIKey, parsedDate, baseCurrencyCode, strings.Join(targetCurrencyCode, ","))
 
-	resp, err := http.Get(requestUrl)
+	apiResp, err := fc.fetch(ctx, requestUrl)
+	if err != nil {
+		return result, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	for tcc, rate := range apiResp.Rates {
+		curExchange, err := internal.NewExchange(baseCurrencyCode, tcc, rate, date)
+		if err != nil {
+			return result, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+		curExchange.FetchedAt = time.Now()
+
+		result = append(result, curExchange)
+	}
+
+	return result, nil
+}
+
+func (fc *ExchangeExternalAPI) fetch(ctx context.Context, requestUrl string) (RateResponse, error) {
+	op := "FreeCurrencyAPI.exchange.fetch"
+	ctx, span := tracing.StartKind(ctx, "GET "+providerName, trace.SpanKindClient,
+		tracing.ProviderKey.String(providerName),
+		tracing.CircuitStateKey.String(fc.circuit.State()))
+	defer span.End()
+
+	probe, err := fc.circuit.allow()
 	if err != nil {
-		return result, fmt.Errorf("%s: %s", op, err)
+		return RateResponse{}, tracing.Error(span, fmt.Errorf("%s: %w: %w", op, upstreamUnavailable(), err))
+	}
+	if probe {
+		defer fc.circuit.release()
+	}
+
+	startedAt := time.Now()
+	apiResp, err := fc.doFetch(ctx, requestUrl)
+	metrics.ObserveUpstreamCall(providerName, startedAt, err)
+	if err != nil {
+		slog.WarnContext(ctx, "upstream request failed", "provider", providerName,
+			"duration_ms", time.Since(startedAt).Milliseconds(), "circuit", fc.circuit.State(), "error", err)
+		return RateResponse{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	slog.DebugContext(ctx, "upstream request", "provider", providerName,
+		"duration_ms", time.Since(startedAt).Milliseconds(), "rates", len(apiResp.Rates))
+
+	return apiResp, nil
+}
+
+func (fc *ExchangeExternalAPI) doFetch(ctx context.Context, requestUrl string) (RateResponse, error) {
+	op := "FreeCurrencyAPI.exchange.doFetch"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return RateResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if requestID := logger.RequestID(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := fc.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			fc.circuit.failure()
+		}
+		// В url.Error попадает адрес запроса вместе с ключом апи
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return RateResponse{}, fmt.Errorf("%s: %w: %w", op, upstreamUnavailable(), err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		err := fmt.Sprintf("%s: Не удалось получить данные со стороннего апи. Ответ: %s", op, resp.Status)
-		return result, fmt.Errorf("%s: %s", op, err)
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			fc.circuit.failure()
+		} else {
+			fc.circuit.success()
+		}
+		err := internal.ErrUpstreamError.With(map[string]any{"provider": providerName, "status": resp.StatusCode})
+		return RateResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fc.circuit.failure()
+		return RateResponse{}, fmt.Errorf("%s: %w: %w", op, upstreamUnavailable(), err)
 	}
 
-	body, _ := io.ReadAll(resp.Body)
+	fc.circuit.success()
 
 	var apiResp RateResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return result, fmt.Errorf("%s: %s", op, err)
+		upstreamErr := internal.ErrUpstreamError.With(map[string]any{"provider": providerName})
+		return RateResponse{}, fmt.Errorf("%s: %w: %w", op, upstreamErr, err)
 	}
 
-	for tcc, rate := range apiResp.Rates {
-		curExchange, err := internal.NewExchange(baseCurrencyCode, tcc, rate, date)
-		if err != nil {
-			return result, fmt.Errorf("%s: %s", op, err)
-		}
-
-		result = append(result, curExchange)
-	}
+	return apiResp, nil
+}
 
-	return result, nil
+func upstreamUnavailable() error {
+	return internal.ErrUpstreamUnavailable.With(map[string]any{"provider": providerName})
 }
@@ -0,0 +1,182 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	English Lang = "en"
+	Russian Lang = "ru"
+)
+
+const DefaultLang Lang = English
+
+// catalog хранит шаблоны сообщений по коду ошибки. Плейсхолдеры вида
+// {currency} заменяются значениями из details ошибки.
+var catalog = map[string]map[Lang]string{
+	"invalid_argument": {
+		English: "Invalid request parameters",
+		Russian: "Неверные параметры запроса",
+	},
+	"missing_parameter": {
+		English: "Required parameter {name} is missing",
+		Russian: "Не передан обязательный параметр {name}",
+	},
+	"invalid_currency_code": {
+		English: "Currency code {currency} must consist of 3 letters",
+		Russian: "Код валюты {currency} должен состоять из 3 букв",
+	},
+	"same_currency": {
+		English: "Base and target currencies must differ",
+		Russian: "Основная и второстепенная валюты должны различаться",
+	},
+	"invalid_rate": {
+		English: "Rate must be positive",
+		Russian: "Курс должен быть положительным",
+	},
+	"invalid_amount": {
+		English: "Amount must be positive",
+		Russian: "Сумма должна быть положительной",
+	},
+	"invalid_action_type": {
+		English: "Action type {type} must look like namespace.event and be at most 64 characters long",
+		Russian: "Тип действия {type} должен иметь вид namespace.event и быть не длиннее 64 символов",
+	},
+	"unknown_action_type": {
+		English: "Unknown action type {type}",
+		Russian: "Неизвестный тип действия {type}",
+	},
+	"invalid_time_range": {
+		English: "The start of the period must be before its end",
+		Russian: "Начало периода должно быть раньше его конца",
+	},
+	"range_too_large": {
+		English: "The period must not be longer than {max_days} days",
+		Russian: "Период не может быть длиннее {max_days} дней",
+	},
+	"unsupported_format": {
+		English: "Format {format} is not supported, use json, csv, xml or ndjson",
+		Russian: "Формат {format} не поддерживается, используйте json, csv, xml или ndjson",
+	},
+	"invalid_date": {
+		English: "Date {date} must be in YYYY-MM-DD format",
+		Russian: "Дата {date} должна быть в формате ГГГГ-ММ-ДД",
+	},
+	"unsupported_currency": {
+		English: "Currency {currency} is not supported",
+		Russian: "Отсутствует такая валюта в системе: {currency}",
+	},
+	"date_in_future": {
+		English: "Rates for {date} are not published yet, the latest available date is {today}",
+		Russian: "Курсы за {date} ещё не опубликованы, последняя доступная дата - {today}",
+	},
+	"date_too_early": {
+		English: "Date {date} is before {earliest}, the earliest date covered by {provider}",
+		Russian: "Дата {date} раньше {earliest}, первой даты с курсами у {provider}",
+	},
+	"not_business_day": {
+		English: "Date {date} is a weekend or holiday, rates are not published",
+		Russian: "{date} - выходной или праздничный день, курсы не публикуются",
+	},
+	"not_found": {
+		English: "The requested rate was not found",
+		Russian: "Запрошенный курс не найден",
+	},
+	"subscription_not_found": {
+		English: "Subscription {subscription} was not found",
+		Russian: "Подписка {subscription} не найдена",
+	},
+	"invalid_webhook_url": {
+		English: "Webhook URL must be an absolute http or https address of a public host",
+		Russian: "Адрес вебхука должен быть абсолютным адресом http или https публичного хоста",
+	},
+	"unauthorized": {
+		English: "Invalid API key",
+		Russian: "Не верный API ключ",
+	},
+	"upstream_error": {
+		English: "The rate provider returned an error",
+		Russian: "Сторонний сервис курсов вернул ошибку",
+	},
+	"upstream_unavailable": {
+		English: "The rate provider is unavailable, try again later",
+		Russian: "Сторонний сервис курсов недоступен, попробуйте позже",
+	},
+	"internal": {
+		English: "Internal server error",
+		Russian: "Внутренняя ошибка сервера",
+	},
+}
+
+// Message возвращает сообщение для кода на языке lang. Если перевода нет,
+// используется DefaultLang, а для неизвестного кода - сообщение internal.
+func Message(lang Lang, code string, details map[string]any) string {
+	translations, ok := catalog[code]
+	if !ok {
+		translations = catalog["internal"]
+	}
+
+	template, ok := translations[lang]
+	if !ok {
+		template = translations[DefaultLang]
+	}
+
+	if len(details) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+
+	replacements := make([]string, 0, len(details)*2)
+	for key, value := range details {
+		replacements = append(replacements, "{"+key+"}", fmt.Sprint(value))
+	}
+
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+// Parse принимает тег языка вида "ru", "ru-RU" или "en_US".
+func Parse(tag string) (Lang, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+
+	switch Lang(primary) {
+	case English, Russian:
+		return Lang(primary), true
+	}
+
+	return "", false
+}
+
+// Negotiate выбирает язык ответа: явный параметр lang важнее заголовка
+// Accept-Language, в котором учитываются веса q.
+func Negotiate(langParam, acceptLanguage string) Lang {
+	if lang, ok := Parse(langParam); ok {
+		return lang
+	}
+
+	best := DefaultLang
+	bestWeight := 0.0
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+
+		lang, ok := Parse(tag)
+		if ok && weight > bestWeight {
+			best, bestWeight = lang, weight
+		}
+	}
+
+	return best
+}
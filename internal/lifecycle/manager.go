@@ -0,0 +1,93 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type hook struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// Manager запускает сервис и по SIGINT/SIGTERM или при его завершении
+// останавливает зарегистрированные компоненты в порядке регистрации.
+// У каждого компонента свой дедлайн, поэтому долгая остановка одного не
+// отнимает время у следующих.
+type Manager struct {
+	hookTimeout time.Duration
+	hooks       []hook
+}
+
+// NewManager создаёт менеджер, hookTimeout - дедлайн остановки компонента по
+// умолчанию.
+func NewManager(hookTimeout time.Duration) *Manager {
+	return &Manager{hookTimeout: hookTimeout}
+}
+
+func (m *Manager) OnShutdown(name string, stop func(ctx context.Context) error) {
+	m.OnShutdownWithin(name, m.hookTimeout, stop)
+}
+
+// OnShutdownWithin регистрирует компонент с собственным дедлайном остановки.
+func (m *Manager) OnShutdownWithin(name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	m.hooks = append(m.hooks, hook{name: name, timeout: timeout, stop: stop})
+}
+
+func (m *Manager) Run(serve func() error) error {
+	op := "lifecycle.manager.Run"
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve()
+	}()
+
+	var runErr error
+	select {
+	case <-signalCtx.Done():
+		slog.Info("shutdown signal received")
+	case err := <-serveErr:
+		if err != nil {
+			runErr = fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	stopSignals()
+
+	return errors.Join(runErr, m.Shutdown())
+}
+
+func (m *Manager) Shutdown() error {
+	op := "lifecycle.manager.Shutdown"
+
+	var errs []error
+	for _, h := range m.hooks {
+		startedAt := time.Now()
+
+		err := m.stop(h)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", op, h.name, err))
+			slog.Error("component shutdown failed", "op", op, "component", h.name, "error", err)
+			continue
+		}
+
+		slog.Info("component stopped", "component", h.name, "duration", time.Since(startedAt).Round(time.Millisecond))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) stop(h hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	return h.stop(ctx)
+}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FormatJSON string = "json"
+	FormatText string = "text"
+)
+
+type requestIDKey struct{}
+
+// Setup настраивает slog.Default. Уровень: debug, info, warn, error;
+// формат: json или text. Пустые значения означают info и json.
+func Setup(level, format string) error {
+	op := "logger.logger.Setup"
+
+	handler, err := NewHandler(os.Stdout, level, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
+
+func NewHandler(w io.Writer, level, format string) (slog.Handler, error) {
+	op := "logger.logger.NewHandler"
+
+	var slogLevel slog.Level
+	if level != "" {
+		err := slogLevel.UnmarshalText([]byte(level))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: slogLevel}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("%s: unknown log format %q", op, format)
+	}
+
+	return contextHandler{Handler: handler}, nil
+}
+
+const requestIDMaxLength int = 128
+
+// ValidRequestID проверяет идентификатор запроса, пришедший от клиента:
+// непустой, не длиннее requestIDMaxLength и из печатных ASCII символов.
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > requestIDMaxLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// contextHandler добавляет к записи request_id и trace_id из контекста, поэтому
+// достаточно логировать через slog.*Context с контекстом запроса.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace string = "exchangerate"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Количество обработанных http запросов.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Время обработки http запросов.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Количество обработанных gRPC запросов.",
+	}, []string{"method", "code"})
+
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Время обработки gRPC запросов.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Время выполнения запросов к бд по методам хранилищ.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"storage", "method"})
+
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "requests_total",
+		Help:      "Количество запросов к сторонним апи курсов.",
+	}, []string{"provider"})
+
+	upstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "errors_total",
+		Help:      "Количество неудачных запросов к сторонним апи курсов.",
+	}, []string{"provider"})
+
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "request_duration_seconds",
+		Help:      "Время ответа сторонних апи курсов.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"provider"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Поиск курсов в бд перед обращением к стороннему апи, result = hit или miss.",
+	}, []string{"cache", "result"})
+
+	schedulerRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "runs_total",
+		Help:      "Количество запусков задач планировщика.",
+	}, []string{"job", "result"})
+
+	schedulerLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix время последнего успешного запуска задачи планировщика.",
+	}, []string{"job"})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ObserveHTTPRequest(route, method string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, statusLabel).Inc()
+	httpDuration.WithLabelValues(route, method, statusLabel).Observe(duration.Seconds())
+}
+
+func ObserveGRPCRequest(method, code string, duration time.Duration) {
+	grpcRequests.WithLabelValues(method, code).Inc()
+	grpcDuration.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+// ObserveDBQuery рассчитан на вызов через defer:
+//
+//	defer metrics.ObserveDBQuery("exchange", "Get", time.Now())
+func ObserveDBQuery(storage, method string, startedAt time.Time) {
+	dbQueryDuration.WithLabelValues(storage, method).Observe(time.Since(startedAt).Seconds())
+}
+
+func ObserveUpstreamCall(provider string, startedAt time.Time, err error) {
+	upstreamRequests.WithLabelValues(provider).Inc()
+	upstreamDuration.WithLabelValues(provider).Observe(time.Since(startedAt).Seconds())
+	if err != nil {
+		upstreamErrors.WithLabelValues(provider).Inc()
+	}
+}
+
+func ObserveCacheLookups(cache string, hits, misses int) {
+	cacheLookups.WithLabelValues(cache, "hit").Add(float64(hits))
+	cacheLookups.WithLabelValues(cache, "miss").Add(float64(misses))
+}
+
+func ObserveSchedulerRun(job string, err error) {
+	if err != nil {
+		schedulerRuns.WithLabelValues(job, "error").Inc()
+		return
+	}
+
+	schedulerRuns.WithLabelValues(job, "success").Inc()
+	schedulerLastSuccess.WithLabelValues(job).SetToCurrentTime()
+}
+
+// RegisterGaugeFunc публикует значение, которое считается в момент сбора метрик.
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn)
+}
+
+func RegisterCounterFunc(name, help string, fn func() float64) {
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn)
+}
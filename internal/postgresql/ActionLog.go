@@ -3,9 +3,21 @@ package postgresql
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+)
+
+const (
+	actionLogPartitionPrefix  string = "exchange_rates_log_p"
+	actionLogPartitionFormat  string = "20060102"
+	actionLogDefaultPartition string = "exchange_rates_log_default"
 )
 
 type ActionLogStorage struct {
@@ -18,12 +30,272 @@ func NewActionLogStorage(pgPool *pgxpool.Pool) *ActionLogStorage {
 
 func (ls *ActionLogStorage) Set(ctx context.Context, ActionLog internal.ActionLog) error {
 	op := "postgresql.logDb.Set"
+	defer metrics.ObserveDBQuery("action_log", "Set", time.Now())
+
+	query := `INSERT INTO exchange_rates_log (action_name, api_key, payload, updated_at) VALUES ($1, $2, $3, $4)`
+	_, err := ls.pgPool.Exec(ctx, query, ActionLog.ActionLogType.Action, nullableString(ActionLog.APIKey), ActionLog.Payload, ActionLog.Timestamp)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (ls *ActionLogStorage) SetBatch(ctx context.Context, ActionLogs []internal.ActionLog) error {
+	op := "postgresql.logDb.SetBatch"
+	defer metrics.ObserveDBQuery("action_log", "SetBatch", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	_, err := ls.pgPool.CopyFrom(
+		ctx,
+		pgx.Identifier{"exchange_rates_log"},
+		[]string{"action_name", "api_key", "payload", "updated_at"},
+		pgx.CopyFromSlice(len(ActionLogs), func(i int) ([]any, error) {
+			actionLog := ActionLogs[i]
+			return []any{actionLog.ActionLogType.Action, nullableString(actionLog.APIKey), actionLog.Payload, actionLog.Timestamp}, nil
+		}),
+	)
+	if err != nil {
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return nil
+}
+
+func (ls *ActionLogStorage) List(ctx context.Context, filter internal.ActionLogFilter) ([]internal.ActionLog, error) {
+	op := "postgresql.logDb.List"
+	defer metrics.ObserveDBQuery("action_log", "List", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	conditions := []string{}
+	args := []any{}
+	addArg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(filter.Types) > 0 {
+		typeConditions := make([]string, 0, len(filter.Types))
+		for _, actionType := range filter.Types {
+			if namespace, ok := strings.CutSuffix(actionType, ".*"); ok {
+				typeConditions = append(typeConditions, "action_name LIKE "+addArg(namespace+".%"))
+			} else {
+				typeConditions = append(typeConditions, "action_name = "+addArg(actionType))
+			}
+		}
+		conditions = append(conditions, "("+strings.Join(typeConditions, " OR ")+")")
+	}
+
+	if filter.APIKey != "" {
+		conditions = append(conditions, "api_key = "+addArg(filter.APIKey))
+	}
+
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "updated_at >= "+addArg(filter.From))
+	}
+
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "updated_at < "+addArg(filter.To))
+	}
+
+	query := `SELECT id, action_name, COALESCE(api_key, ''), payload, updated_at
+              FROM exchange_rates_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY updated_at DESC, id DESC LIMIT " + addArg(filter.Limit) + " OFFSET " + addArg(filter.Offset)
+
+	rows, err := ls.pgPool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+	defer rows.Close()
+
+	result := []internal.ActionLog{}
+	for rows.Next() {
+		actionLog, err := scanActionLog(rows)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		result = append(result, actionLog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return result, nil
+}
+
+func (ls *ActionLogStorage) CreatePartition(ctx context.Context, day time.Time) error {
+	op := "postgresql.logDb.CreatePartition"
+	defer metrics.ObserveDBQuery("action_log", "CreatePartition", time.Now())
+	from := day.UTC().Truncate(24 * time.Hour)
+	to := from.AddDate(0, 0, 1)
+
+	partition := pgx.Identifier{actionLogPartitionPrefix + from.Format(actionLogPartitionFormat)}.Sanitize()
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF exchange_rates_log FOR VALUES FROM ('%s') TO ('%s')`,
+		partition, from.Format(time.DateOnly), to.Format(time.DateOnly))
+
+	_, err := ls.pgPool.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (ls *ActionLogStorage) ListPartitions(ctx context.Context) ([]internal.ActionLogPartition, error) {
+	op := "postgresql.logDb.ListPartitions"
+	defer metrics.ObserveDBQuery("action_log", "ListPartitions", time.Now())
+
+	query := `SELECT child.relname
+              FROM pg_inherits
+              JOIN pg_class parent ON parent.oid = pg_inherits.inhparent
+              JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+              WHERE parent.relname = 'exchange_rates_log'
+              ORDER BY child.relname`
+
+	rows, err := ls.pgPool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	result := []internal.ActionLogPartition{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if name == actionLogDefaultPartition {
+			result = append(result, internal.ActionLogPartition{Name: name, IsDefault: true})
+			continue
+		}
+
+		suffix, ok := strings.CutPrefix(name, actionLogPartitionPrefix)
+		if !ok {
+			continue
+		}
+
+		from, err := time.Parse(actionLogPartitionFormat, suffix)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, internal.ActionLogPartition{
+			Name: name,
+			From: from,
+			To:   from.AddDate(0, 0, 1),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+func (ls *ActionLogStorage) ReadPartition(ctx context.Context, partition internal.ActionLogPartition, before time.Time, fn func(internal.ActionLog) error) error {
+	op := "postgresql.logDb.ReadPartition"
+	defer metrics.ObserveDBQuery("action_log", "ReadPartition", time.Now())
 
-	query := `INSERT INTO exchange_rates_log (action_name, updated_at) VALUES ($1, $2)`
-	_, err := ls.pgPool.Exec(ctx, query, ActionLog.ActionLogType.Action, ActionLog.Timestamp)
+	query := fmt.Sprintf(`SELECT id, action_name, COALESCE(api_key, ''), payload, updated_at
+              FROM %s
+              WHERE updated_at < $1
+              ORDER BY updated_at, id`, pgx.Identifier{partition.Name}.Sanitize())
+
+	if !partition.IsDefault {
+		before = partition.To
+	}
+
+	rows, err := ls.pgPool.Query(ctx, query, before)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		actionLog, err := scanActionLog(rows)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		err = fn(actionLog)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+func (ls *ActionLogStorage) DropPartition(ctx context.Context, partition internal.ActionLogPartition) error {
+	op := "postgresql.logDb.DropPartition"
+	defer metrics.ObserveDBQuery("action_log", "DropPartition", time.Now())
+
+	if partition.IsDefault {
+		return fmt.Errorf("%s: Партицию по умолчанию удалять нельзя", op)
+	}
+
+	query := fmt.Sprintf(`DROP TABLE IF EXISTS %s`, pgx.Identifier{partition.Name}.Sanitize())
+	_, err := ls.pgPool.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (ls *ActionLogStorage) DeleteFromPartition(ctx context.Context, partition internal.ActionLogPartition, before time.Time) error {
+	op := "postgresql.logDb.DeleteFromPartition"
+	defer metrics.ObserveDBQuery("action_log", "DeleteFromPartition", time.Now())
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE updated_at < $1`, pgx.Identifier{partition.Name}.Sanitize())
+	_, err := ls.pgPool.Exec(ctx, query, before)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanActionLog(rows pgx.Rows) (internal.ActionLog, error) {
+	var scanID int64
+	var scanAction string
+	var scanAPIKey string
+	var scanPayload map[string]any
+	var scanTimestamp time.Time
+
+	err := rows.Scan(&scanID, &scanAction, &scanAPIKey, &scanPayload, &scanTimestamp)
+	if err != nil {
+		return internal.ActionLog{}, err
+	}
+
+	actionLog := internal.ActionLog{
+		ID:            internal.ActionLogID(strconv.FormatInt(scanID, 10)),
+		ActionLogType: internal.ActionLogType{Action: scanAction},
+		APIKey:        scanAPIKey,
+		Payload:       scanPayload,
+		Timestamp:     scanTimestamp,
+	}
+
+	return actionLog, nil
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
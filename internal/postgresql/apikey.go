@@ -2,52 +2,143 @@ package postgresql
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
 )
 
 type APIKeyStorage struct {
 	pgPool *pgxpool.Pool
+
+	mu sync.Mutex
+	// profiles - разобранные профили наценки по имени. Профиль разбирается
+	// заново, только когда его настройки в бд изменились.
+	profiles map[string]cachedProfile
+}
+
+type cachedProfile struct {
+	config  string
+	profile *internal.PricingProfile
 }
 
 func NewAPIKeyStorage(pgPool *pgxpool.Pool) *APIKeyStorage {
-	return &APIKeyStorage{pgPool: pgPool}
+	return &APIKeyStorage{pgPool: pgPool, profiles: make(map[string]cachedProfile)}
 }
 
 func (es *APIKeyStorage) Get(ctx context.Context, APIKey string) (internal.APIKey, error) {
 	op := "postgresql.apikey.GetExchange"
+	defer metrics.ObserveDBQuery("apikey", "Get", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
-	query := `SELECT key
-              FROM api_keys 
-              WHERE key = $1`
+	query := `SELECT k.key, k.is_admin, p.name, COALESCE(p.default_spread_bps, 0),
+                     COALESCE(p.currency_groups, '{}'::jsonb), COALESCE(p.spreads, '{}'::jsonb)
+              FROM api_keys k
+              LEFT JOIN pricing_profiles p ON p.name = k.pricing_profile
+              WHERE k.key = $1`
 
 	result := internal.NewAPIKey(APIKey)
 
+	var (
+		profileName      *string
+		defaultSpreadBps float64
+		groups           []byte
+		spreads          []byte
+	)
+
 	err := es.pgPool.QueryRow(ctx, query, APIKey).Scan(
 		&result.Key,
+		&result.Admin,
+		&profileName,
+		&defaultSpreadBps,
+		&groups,
+		&spreads,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return result, nil
 		}
-		return result, fmt.Errorf("%s: %s", op, err)
+		return result, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if profileName != nil {
+		result.Pricing = es.pricingProfile(*profileName, defaultSpreadBps, groups, spreads)
 	}
 
 	result.Valid = true
 	return result, nil
 }
 
+// pricingProfile возвращает разобранный профиль наценки. Неверный профиль не
+// должен ломать запросы всех ключей, которым он назначен, поэтому ошибка
+// пишется в лог один раз на каждую версию настроек, а ключ обслуживается без
+// наценки.
+func (es *APIKeyStorage) pricingProfile(name string, defaultSpreadBps float64, groups, spreads []byte) *internal.PricingProfile {
+	op := "postgresql.apikey.pricingProfile"
+
+	config := strconv.FormatFloat(defaultSpreadBps, 'g', -1, 64) + "\x00" + string(groups) + "\x00" + string(spreads)
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	cached, ok := es.profiles[name]
+	if ok && cached.config == config {
+		return cached.profile
+	}
+
+	profile, err := parsePricingProfile(name, defaultSpreadBps, groups, spreads)
+	if err != nil {
+		slog.Warn("invalid pricing profile, serving keys without spread", "op", op, "profile", name, "error", err)
+	}
+	es.profiles[name] = cachedProfile{config: config, profile: profile}
+
+	return profile
+}
+
+func parsePricingProfile(name string, defaultSpreadBps float64, rawGroups, rawSpreads []byte) (*internal.PricingProfile, error) {
+	op := "postgresql.apikey.parsePricingProfile"
+
+	var groups map[string][]string
+	err := json.Unmarshal(rawGroups, &groups)
+	if err != nil {
+		return nil, fmt.Errorf("%s: profile %s: currency_groups: %w", op, name, err)
+	}
+
+	var spreads map[string]float64
+	err = json.Unmarshal(rawSpreads, &spreads)
+	if err != nil {
+		return nil, fmt.Errorf("%s: profile %s: spreads: %w", op, name, err)
+	}
+
+	profile, err := internal.NewPricingProfile(name, defaultSpreadBps, groups, spreads)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return profile, nil
+}
+
 func (es *APIKeyStorage) Set(ctx context.Context, APIKey internal.APIKey) error {
 	op := "postgresql.apikey.SetAPIKey"
+	defer metrics.ObserveDBQuery("apikey", "Set", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
-	query := `INSERT INTO api_keys (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`
-	_, err := es.pgPool.Exec(ctx, query, APIKey.Key)
+	query := `INSERT INTO api_keys (key, is_admin) VALUES ($1, $2)
+              ON CONFLICT (key) DO UPDATE SET is_admin = api_keys.is_admin OR EXCLUDED.is_admin`
+	_, err := es.pgPool.Exec(ctx, query, APIKey.Key, APIKey.Admin)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 	}
 
 	return nil
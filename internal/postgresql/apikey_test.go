@@ -0,0 +1,45 @@
+package postgresql
+
+import "testing"
+
+func TestPricingProfile(t *testing.T) {
+	storage := NewAPIKeyStorage(nil)
+
+	profile := storage.pricingProfile("partner", 10, []byte(`{"majors":["USD","EUR"]}`), []byte(`{"majors":20}`))
+	if profile == nil {
+		t.Fatal("pricingProfile() = nil for valid profile")
+	}
+	if got := profile.SpreadBps("USD", "EUR"); got != 20 {
+		t.Errorf("SpreadBps(USD, EUR) = %v, want 20", got)
+	}
+
+	if cached := storage.pricingProfile("partner", 10, []byte(`{"majors":["USD","EUR"]}`), []byte(`{"majors":20}`)); cached != profile {
+		t.Errorf("pricingProfile() reparsed unchanged profile")
+	}
+
+	changed := storage.pricingProfile("partner", 10, []byte(`{"majors":["USD","EUR"]}`), []byte(`{"majors":30}`))
+	if got := changed.SpreadBps("USD", "EUR"); got != 30 {
+		t.Errorf("SpreadBps(USD, EUR) after change = %v, want 30", got)
+	}
+}
+
+func TestPricingProfileInvalidFallsBack(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  string
+		spreads string
+	}{
+		{"unknown currency", `{"majors":["XXX"]}`, `{"majors":20}`},
+		{"unknown group", `{}`, `{"majors":20}`},
+		{"groups not an object", `["USD"]`, `{}`},
+		{"spread not a number", `{}`, `{"USD/EUR":"20"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewAPIKeyStorage(nil)
+			if profile := storage.pricingProfile("partner", 10, []byte(tt.groups), []byte(tt.spreads)); profile != nil {
+				t.Errorf("pricingProfile() = %+v, want nil", profile)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+)
+
+type CandleStorage struct {
+	pgPool *pgxpool.Pool
+}
+
+func NewCandleStorage(pgPool *pgxpool.Pool) *CandleStorage {
+	return &CandleStorage{pgPool: pgPool}
+}
+
+// Candles собирает свечи из наблюдений за [start, end). Наблюдения, которые
+// покрыты сохранёнными свёртками того же интервала, пропускаются.
+func (cs *CandleStorage) Candles(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, interval internal.CandleInterval, start, end time.Time) ([]internal.Candle, error) {
+	op := "postgresql.candle.Candles"
+	defer metrics.ObserveDBQuery("candle", "Candles", time.Now())
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrencyKey.String(targetCurrencyCode),
+		tracing.DateKey.String(start.Format(time.DateOnly)+"/"+end.Format(time.DateOnly)))
+	defer span.End()
+
+	query := `SELECT date_trunc($3, o.observed_at, 'UTC') AS bucket_start,
+                     (array_agg(o.rate ORDER BY o.observed_at))[1],
+                     max(o.rate),
+                     min(o.rate),
+                     (array_agg(o.rate ORDER BY o.observed_at DESC))[1],
+                     count(*)
+              FROM rate_observations o
+              WHERE o.base_currency = $1 AND o.target_currency = $2
+                AND o.observed_at >= $4 AND o.observed_at < $5
+                AND NOT EXISTS (
+                    SELECT 1 FROM rate_candles c
+                    WHERE c.base_currency = o.base_currency AND c.target_currency = o.target_currency
+                      AND c.bucket = $3 AND o.observed_at >= c.bucket_start AND o.observed_at < c.bucket_end
+                )
+              GROUP BY bucket_start
+              ORDER BY bucket_start`
+
+	rows, err := cs.pgPool.Query(ctx, query, baseCurrencyCode, targetCurrencyCode, string(interval), start, end)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+	defer rows.Close()
+
+	candles := []internal.Candle{}
+	for rows.Next() {
+		candle := internal.Candle{Interval: interval}
+
+		err = rows.Scan(&candle.Start, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Count)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		candle.Start = candle.Start.UTC()
+		candle.End = interval.Next(candle.Start)
+
+		candle, err = withCandleCurrencies(candle, baseCurrencyCode, targetCurrencyCode)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		candles = append(candles, candle)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return candles, nil
+}
+
+// Rollups возвращает сохранённые свечи интервала, начинающиеся в [start, end).
+func (cs *CandleStorage) Rollups(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, interval internal.CandleInterval, start, end time.Time) ([]internal.Candle, error) {
+	op := "postgresql.candle.Rollups"
+	defer metrics.ObserveDBQuery("candle", "Rollups", time.Now())
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrencyKey.String(targetCurrencyCode),
+		tracing.DateKey.String(start.Format(time.DateOnly)+"/"+end.Format(time.DateOnly)))
+	defer span.End()
+
+	query := `SELECT bucket_start, bucket_end, open, high, low, close, observations
+              FROM rate_candles
+              WHERE base_currency = $1 AND target_currency = $2 AND bucket = $3
+                AND bucket_start >= $4 AND bucket_start < $5
+              ORDER BY bucket_start`
+
+	rows, err := cs.pgPool.Query(ctx, query, baseCurrencyCode, targetCurrencyCode, string(interval), start, end)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+	defer rows.Close()
+
+	candles := []internal.Candle{}
+	for rows.Next() {
+		candle := internal.Candle{Interval: interval}
+
+		err = rows.Scan(&candle.Start, &candle.End, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Count)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		candle.Start = candle.Start.UTC()
+		candle.End = candle.End.UTC()
+
+		candle, err = withCandleCurrencies(candle, baseCurrencyCode, targetCurrencyCode)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		candles = append(candles, candle)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return candles, nil
+}
+
+// SetRollups сохраняет закрытые свечи одним батчем.
+func (cs *CandleStorage) SetRollups(ctx context.Context, candles []internal.Candle) error {
+	op := "postgresql.candle.SetRollups"
+	defer metrics.ObserveDBQuery("candle", "SetRollups", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `INSERT INTO rate_candles (base_currency, target_currency, bucket, bucket_start, bucket_end, open, high, low, close, observations)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+              ON CONFLICT (base_currency, target_currency, bucket, bucket_start)
+              DO UPDATE SET bucket_end = EXCLUDED.bucket_end, open = EXCLUDED.open, high = EXCLUDED.high,
+                            low = EXCLUDED.low, close = EXCLUDED.close, observations = EXCLUDED.observations`
+
+	batch := &pgx.Batch{}
+	for _, candle := range candles {
+		batch.Queue(query, candle.BaseCurrency.Code, candle.TargetCurrency.Code, string(candle.Interval),
+			candle.Start, candle.End, candle.Open, candle.High, candle.Low, candle.Close, candle.Count)
+	}
+
+	err := cs.pgPool.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return nil
+}
+
+func withCandleCurrencies(candle internal.Candle, baseCurrencyCode, targetCurrencyCode string) (internal.Candle, error) {
+	var err error
+
+	candle.BaseCurrency, err = internal.NewCurrency(baseCurrencyCode)
+	if err != nil {
+		return internal.Candle{}, err
+	}
+
+	candle.TargetCurrency, err = internal.NewCurrency(targetCurrencyCode)
+	if err != nil {
+		return internal.Candle{}, err
+	}
+
+	return candle, nil
+}
@@ -9,55 +9,246 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
 )
 
+// ExchangeStorage хранит курсы по календарным датам. Даты передаются как
+// полночь UTC (см. internal.Clock.Date) и сравниваются как DATE, поэтому
+// результат не зависит от часового пояса сессии бд.
 type ExchangeStorage struct {
 	pgPool *pgxpool.Pool
+	clock  internal.Clock
 }
 
-func NewExchangeStorage(pgPool *pgxpool.Pool) *ExchangeStorage {
-	return &ExchangeStorage{pgPool: pgPool}
+func NewExchangeStorage(pgPool *pgxpool.Pool, clock internal.Clock) *ExchangeStorage {
+	return &ExchangeStorage{pgPool: pgPool, clock: clock}
 }
 
 func (es *ExchangeStorage) Get(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, date time.Time) (internal.Exchange, error) {
 	op := "postgresql.exchange.GetExchange"
+	defer metrics.ObserveDBQuery("exchange", "Get", time.Now())
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrencyKey.String(targetCurrencyCode), tracing.DateKey.String(date.Format(time.DateOnly)))
+	defer span.End()
 
-	query := `SELECT rate, updated_at 
+	query := `SELECT rate, updated_at, fetched_at
               FROM exchange_rates 
-              WHERE baseCurrency = $1 AND targetCurrency = $2 AND DATE(updated_at) = DATE($3)`
+              WHERE baseCurrency = $1 AND targetCurrency = $2 AND updated_at = $3::date`
 
 	var scanRate float64
 	var scanTimestamp time.Time
+	var scanFetchedAt time.Time
 	err := es.pgPool.QueryRow(ctx, query, baseCurrencyCode, targetCurrencyCode, date).Scan(
 		&scanRate,
 		&scanTimestamp,
+		&scanFetchedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return internal.Exchange{}, nil
 		}
-		return internal.Exchange{}, fmt.Errorf("%s: %s", op, err)
+		return internal.Exchange{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 	}
 
 	exchange, err := internal.NewExchange(baseCurrencyCode, targetCurrencyCode, scanRate, scanTimestamp)
 	if err != nil {
-		return internal.Exchange{}, fmt.Errorf("%s: %s", op, err)
+		return internal.Exchange{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
 	}
+	exchange.FetchedAt = scanFetchedAt
 
 	return exchange, nil
 }
 
-func (es *ExchangeStorage) Set(ctx context.Context, exchange internal.Exchange) error {
+func (es *ExchangeStorage) GetPrevious(ctx context.Context, baseCurrencyCode, targetCurrencyCode string, date time.Time) (internal.Exchange, error) {
+	op := "postgresql.exchange.GetPrevious"
+	defer metrics.ObserveDBQuery("exchange", "GetPrevious", time.Now())
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrencyKey.String(targetCurrencyCode), tracing.DateKey.String(date.Format(time.DateOnly)))
+	defer span.End()
+
+	query := `SELECT rate, updated_at, fetched_at
+              FROM exchange_rates
+              WHERE baseCurrency = $1 AND targetCurrency = $2 AND updated_at < $3::date
+              ORDER BY updated_at DESC
+              LIMIT 1`
+
+	var scanRate float64
+	var scanTimestamp time.Time
+	var scanFetchedAt time.Time
+	err := es.pgPool.QueryRow(ctx, query, baseCurrencyCode, targetCurrencyCode, date).Scan(
+		&scanRate,
+		&scanTimestamp,
+		&scanFetchedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.Exchange{}, nil
+		}
+		return internal.Exchange{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	exchange, err := internal.NewExchange(baseCurrencyCode, targetCurrencyCode, scanRate, scanTimestamp)
+	if err != nil {
+		return internal.Exchange{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+	exchange.FetchedAt = scanFetchedAt
+
+	return exchange, nil
+}
+
+// GetMany возвращает курсы base к нескольким валютам на дату одним запросом.
+// Курсов, которых нет в бд, в результате нет.
+func (es *ExchangeStorage) GetMany(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, date time.Time) ([]internal.Exchange, error) {
+	op := "postgresql.exchange.GetMany"
+	defer metrics.ObserveDBQuery("exchange", "GetMany", time.Now())
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrenciesKey.StringSlice(targetCurrencyCodes), tracing.DateKey.String(date.Format(time.DateOnly)))
+	defer span.End()
+
+	query := `SELECT targetCurrency, rate, updated_at, fetched_at
+              FROM exchange_rates
+              WHERE baseCurrency = $1 AND targetCurrency = ANY($2) AND updated_at = $3::date`
+
+	rows, err := es.pgPool.Query(ctx, query, baseCurrencyCode, targetCurrencyCodes, date)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+	defer rows.Close()
+
+	exchanges := make([]internal.Exchange, 0, len(targetCurrencyCodes))
+	for rows.Next() {
+		var scanTarget string
+		var scanRate float64
+		var scanTimestamp time.Time
+		var scanFetchedAt time.Time
+
+		err = rows.Scan(&scanTarget, &scanRate, &scanTimestamp, &scanFetchedAt)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		exchange, err := internal.NewExchange(baseCurrencyCode, scanTarget, scanRate, scanTimestamp)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+		exchange.FetchedAt = scanFetchedAt
+
+		exchanges = append(exchanges, exchange)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return exchanges, nil
+}
+
+// GetRange возвращает курсы base к targetCurrencyCodes за период с start по
+// end включительно, упорядоченные по дате и валюте.
+func (es *ExchangeStorage) GetRange(ctx context.Context, baseCurrencyCode string, targetCurrencyCodes []string, start, end time.Time) ([]internal.Exchange, error) {
+	op := "postgresql.exchange.GetRange"
+	defer metrics.ObserveDBQuery("exchange", "GetRange", time.Now())
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(baseCurrencyCode), tracing.TargetCurrenciesKey.StringSlice(targetCurrencyCodes),
+		tracing.DateKey.String(start.Format(time.DateOnly)+"/"+end.Format(time.DateOnly)))
+	defer span.End()
+
+	query := `SELECT targetCurrency, rate, updated_at, fetched_at
+              FROM exchange_rates
+              WHERE baseCurrency = $1 AND targetCurrency = ANY($2)
+                AND updated_at BETWEEN $3::date AND $4::date
+              ORDER BY updated_at, targetCurrency`
+
+	rows, err := es.pgPool.Query(ctx, query, baseCurrencyCode, targetCurrencyCodes, start, end)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+	defer rows.Close()
+
+	exchanges := []internal.Exchange{}
+	for rows.Next() {
+		var scanTarget string
+		var scanRate float64
+		var scanTimestamp time.Time
+		var scanFetchedAt time.Time
+
+		err = rows.Scan(&scanTarget, &scanRate, &scanTimestamp, &scanFetchedAt)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+
+		exchange, err := internal.NewExchange(baseCurrencyCode, scanTarget, scanRate, scanTimestamp)
+		if err != nil {
+			return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+		}
+		exchange.FetchedAt = scanFetchedAt
+
+		exchanges = append(exchanges, exchange)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return exchanges, nil
+}
+
+// Set сохраняет курс и сообщает, был ли он добавлен или изменился.
+func (es *ExchangeStorage) Set(ctx context.Context, exchange internal.Exchange) (bool, error) {
 	op := "postgresql.exchange.SetExchange"
+	defer metrics.ObserveDBQuery("exchange", "Set", time.Now())
+	ctx, span := tracing.Start(ctx, op, tracing.BaseCurrencyKey.String(exchange.BaseCurrency.Code), tracing.TargetCurrencyKey.String(exchange.TargetCurrency.Code), tracing.DateKey.String(exchange.Timestamp.Format(time.DateOnly)))
+	defer span.End()
 
-	query := `INSERT INTO exchange_rates (BaseCurrency, TargetCurrency, rate, updated_at) 
-		VALUES ($1, $2, $3, $4)
+	if exchange.FetchedAt.IsZero() {
+		exchange.FetchedAt = es.clock.Now()
+	}
+	fetchedAt := exchange.FetchedAt
+
+	// prev видит строку до вставки, поэтому по нему можно понять, изменился
+	// ли курс. Вместе с курсом записывается наблюдение для свечей, а
+	// сохранённые свёртки, в которые оно попадает, удаляются и будут
+	// пересчитаны при следующем запросе.
+	query := `WITH prev AS (
+			SELECT rate FROM exchange_rates
+			WHERE BaseCurrency = $1 AND TargetCurrency = $2 AND updated_at = $4::date
+		), observation AS (
+			INSERT INTO rate_observations (base_currency, target_currency, rate, observed_at)
+			VALUES ($1, $2, $3, $6)
+			ON CONFLICT ON CONSTRAINT unique_rate_observation DO UPDATE SET rate = EXCLUDED.rate
+		), stale_rollups AS (
+			DELETE FROM rate_candles
+			WHERE base_currency = $1 AND target_currency = $2 AND bucket_start <= $6 AND bucket_end > $6
+		)
+		INSERT INTO exchange_rates (BaseCurrency, TargetCurrency, rate, updated_at, fetched_at) 
+		VALUES ($1, $2, $3, $4::date, $5)
 		ON CONFLICT ON CONSTRAINT unique_exchange_date
-    	DO UPDATE SET rate = EXCLUDED.rate`
-	_, err := es.pgPool.Exec(ctx, query, exchange.BaseCurrency.Code, exchange.TargetCurrency.Code, exchange.Rate, exchange.Timestamp)
+    	DO UPDATE SET rate = EXCLUDED.rate, fetched_at = EXCLUDED.fetched_at
+		RETURNING (SELECT rate FROM prev)`
+
+	var prevRate *float64
+	err := es.pgPool.QueryRow(ctx, query, exchange.BaseCurrency.Code, exchange.TargetCurrency.Code, exchange.Rate, exchange.Timestamp, fetchedAt, exchange.ObservedAt(es.clock)).Scan(&prevRate)
+	if err != nil {
+		return false, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return prevRate == nil || *prevRate != exchange.Rate, nil
+}
+
+func (es *ExchangeStorage) LatestTimestamp(ctx context.Context) (time.Time, error) {
+	op := "postgresql.exchange.LatestTimestamp"
+	defer metrics.ObserveDBQuery("exchange", "LatestTimestamp", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `SELECT MAX(updated_at) FROM exchange_rates`
+
+	var scanTimestamp *time.Time
+	err := es.pgPool.QueryRow(ctx, query).Scan(&scanTimestamp)
 	if err != nil {
-		return fmt.Errorf("%s: %s", op, err)
+		return time.Time{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if scanTimestamp == nil {
+		return time.Time{}, nil
 	}
 
-	return nil
+	return *scanTimestamp, nil
 }
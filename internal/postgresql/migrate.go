@@ -0,0 +1,149 @@
+package postgresql
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID - ключ advisory lock, под которым миграции применяются,
+// чтобы несколько экземпляров сервиса не применяли их одновременно.
+const migrationLockID int64 = 7_345_012_001
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// Migrate применяет к бд миграции из migrations, которых ещё нет в
+// schema_migrations. Каждая миграция выполняется в своей транзакции.
+// Миграции идемпотентны, поэтому базы, созданные до появления
+// schema_migrations, приводятся к текущей схеме без ручных шагов.
+func Migrate(ctx context.Context, pgPool *pgxpool.Pool) error {
+	op := "postgresql.migrate.Migrate"
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	conn, err := pgPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+		if err != nil {
+			slog.Error("releasing migration lock failed", "op", op, "error", err)
+		}
+	}()
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INT PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+    )`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	applied := make(map[int]struct{})
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		applied[version] = struct{}{}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		_, err = tx.Exec(ctx, m.sql)
+		if err == nil {
+			_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name)
+		}
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return fmt.Errorf("%s: migration %s: %w", op, m.name, err)
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: migration %s: %w", op, m.name, err)
+		}
+
+		slog.Info("database migration applied", "version", m.version, "name", m.name)
+	}
+
+	return nil
+}
+
+// loadMigrations читает миграции вида "0001_name.sql" в порядке версий.
+func loadMigrations() ([]migration, error) {
+	op := "postgresql.migrate.loadMigrations"
+
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	migrations := make([]migration, 0, len(names))
+	seen := make(map[int]string, len(names))
+	for _, path := range names {
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "migrations/"), ".sql")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("%s: migration %s has no numeric version", op, name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("%s: migrations %s and %s share version %d", op, other, name, version)
+		}
+		seen[version] = name
+
+		sql, err := migrationFiles.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		migrations = append(migrations, migration{version: version, name: name, sql: string(sql)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
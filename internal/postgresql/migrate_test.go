@@ -0,0 +1,22 @@
+package postgresql
+
+import "testing"
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %s has version %d, want %d", m.name, m.version, i+1)
+		}
+		if m.sql == "" {
+			t.Errorf("migration %s is empty", m.name)
+		}
+	}
+}
@@ -1,3 +1,4 @@
+-- Схема до версионных миграций. В существующих базах таблицы уже есть.
 CREATE TABLE IF NOT EXISTS exchange_rates (
     id SERIAL PRIMARY KEY,
     BaseCurrency VARCHAR(3) NOT NULL,
@@ -0,0 +1,51 @@
+-- Лог действий до партиционирования: у старых баз в нём коды действий из 4
+-- символов и нет api_key и payload. Колонки приводятся к новому виду, а сама
+-- таблица переименовывается и ниже переносится в партиционированную.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'exchange_rates_log' AND relkind = 'r') THEN
+        ALTER TABLE exchange_rates_log ALTER COLUMN action_name TYPE VARCHAR(64);
+        ALTER TABLE exchange_rates_log ADD COLUMN IF NOT EXISTS api_key TEXT;
+        ALTER TABLE exchange_rates_log ADD COLUMN IF NOT EXISTS payload JSONB NOT NULL DEFAULT '{}'::jsonb;
+        ALTER TABLE exchange_rates_log RENAME TO exchange_rates_log_legacy;
+        -- Имена индекса и последовательности освобождаются для новой таблицы
+        ALTER INDEX IF EXISTS exchange_rates_log_pkey RENAME TO exchange_rates_log_legacy_pkey;
+        ALTER SEQUENCE IF EXISTS exchange_rates_log_id_seq RENAME TO exchange_rates_log_legacy_id_seq;
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS exchange_rates_log (
+    id BIGSERIAL,
+    action_name VARCHAR(64) NOT NULL,
+    api_key TEXT,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, updated_at)
+) PARTITION BY RANGE (updated_at);
+
+-- Дневные партиции создаёт сервис, сюда попадают записи, для которых партиции ещё нет
+CREATE TABLE IF NOT EXISTS exchange_rates_log_default PARTITION OF exchange_rates_log DEFAULT;
+
+DO $$
+BEGIN
+    IF to_regclass('exchange_rates_log_legacy') IS NOT NULL THEN
+        INSERT INTO exchange_rates_log (action_name, api_key, payload, updated_at)
+        SELECT CASE action_name
+                   WHEN 'PAIR' THEN 'rate.pair'
+                   WHEN 'DATE' THEN 'rate.date'
+                   ELSE lower(action_name)
+               END,
+               api_key, payload, COALESCE(updated_at, CURRENT_TIMESTAMP)
+        FROM exchange_rates_log_legacy;
+
+        DROP TABLE exchange_rates_log_legacy;
+    END IF;
+END $$;
+
+-- Лог хранит хэш ключа вместо самого ключа, см. internal.HashAPIKey
+UPDATE exchange_rates_log
+SET api_key = 'sha256:' || encode(sha256(convert_to(api_key, 'UTF8')), 'hex')
+WHERE api_key IS NOT NULL AND api_key NOT LIKE 'sha256:%';
+
+CREATE INDEX IF NOT EXISTS exchange_rates_log_action_idx ON exchange_rates_log (action_name, updated_at);
+CREATE INDEX IF NOT EXISTS exchange_rates_log_api_key_idx ON exchange_rates_log (api_key, updated_at);
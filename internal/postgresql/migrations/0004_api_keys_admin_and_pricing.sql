@@ -0,0 +1,14 @@
+-- Ключ администратора видит лог действий всех ключей
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- Профили наценки партнёров. Спреды в базисных пунктах: spreads задаёт их по
+-- парам {"USD/EUR": 10} и по группам валют из currency_groups
+-- {"exotic": ["RUB"]}, default_spread_bps - для остальных пар.
+CREATE TABLE IF NOT EXISTS pricing_profiles (
+    name VARCHAR(64) PRIMARY KEY,
+    default_spread_bps DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (default_spread_bps >= 0 AND default_spread_bps < 10000),
+    currency_groups JSONB NOT NULL DEFAULT '{}'::jsonb,
+    spreads JSONB NOT NULL DEFAULT '{}'::jsonb
+);
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS pricing_profile VARCHAR(64) REFERENCES pricing_profiles (name) ON DELETE SET NULL;
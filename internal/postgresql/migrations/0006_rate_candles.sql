@@ -0,0 +1,31 @@
+-- Наблюдения курсов, из которых строятся свечи: курсы за прошедшие даты
+-- относятся к началу дня, текущие - ко времени получения
+CREATE TABLE IF NOT EXISTS rate_observations (
+    base_currency VARCHAR(3) NOT NULL,
+    target_currency VARCHAR(3) NOT NULL,
+    rate FLOAT NOT NULL,
+    observed_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT unique_rate_observation UNIQUE (base_currency, target_currency, observed_at)
+);
+
+INSERT INTO rate_observations (base_currency, target_currency, rate, observed_at)
+SELECT BaseCurrency, TargetCurrency, rate,
+    CASE WHEN DATE(fetched_at AT TIME ZONE 'UTC') = updated_at THEN fetched_at
+         ELSE updated_at::timestamp AT TIME ZONE 'UTC' END
+FROM exchange_rates
+ON CONFLICT ON CONSTRAINT unique_rate_observation DO NOTHING;
+
+-- Сохранённые недельные и месячные свечи за закрытые периоды
+CREATE TABLE IF NOT EXISTS rate_candles (
+    base_currency VARCHAR(3) NOT NULL,
+    target_currency VARCHAR(3) NOT NULL,
+    bucket VARCHAR(8) NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    bucket_end TIMESTAMPTZ NOT NULL,
+    open FLOAT NOT NULL,
+    high FLOAT NOT NULL,
+    low FLOAT NOT NULL,
+    close FLOAT NOT NULL,
+    observations INT NOT NULL,
+    PRIMARY KEY (base_currency, target_currency, bucket, bucket_start)
+);
@@ -0,0 +1,351 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/metrics"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+)
+
+type SubscriptionStorage struct {
+	pgPool *pgxpool.Pool
+}
+
+func NewSubscriptionStorage(pgPool *pgxpool.Pool) *SubscriptionStorage {
+	return &SubscriptionStorage{pgPool: pgPool}
+}
+
+// subscriptionCondition - условие подписки в колонке conditions.
+type subscriptionCondition struct {
+	Type      string  `json:"type"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Percent   float64 `json:"percent,omitempty"`
+}
+
+const subscriptionColumns string = `id, api_key, url, secret, pairs, conditions, created_at`
+
+func (ss *SubscriptionStorage) Create(ctx context.Context, subscription internal.Subscription) (internal.Subscription, error) {
+	op := "postgresql.subscription.Create"
+	defer metrics.ObserveDBQuery("subscription", "Create", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	conditions := make([]subscriptionCondition, 0, len(subscription.Conditions))
+	for _, condition := range subscription.Conditions {
+		conditions = append(conditions, subscriptionCondition{
+			Type:      string(condition.Type),
+			Threshold: condition.Threshold,
+			Percent:   condition.Percent,
+		})
+	}
+
+	conditionsJSON, err := json.Marshal(conditions)
+	if err != nil {
+		return internal.Subscription{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	query := `INSERT INTO webhook_subscriptions (api_key, url, secret, pairs, conditions, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING id`
+
+	var id int64
+	err = ss.pgPool.QueryRow(ctx, query, subscription.APIKey, subscription.URL, subscription.Secret,
+		subscription.Pairs, conditionsJSON, subscription.CreatedAt).Scan(&id)
+	if err != nil {
+		return internal.Subscription{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	subscription.ID = internal.SubscriptionID(strconv.FormatInt(id, 10))
+
+	return subscription, nil
+}
+
+func (ss *SubscriptionStorage) Get(ctx context.Context, apiKey string, id internal.SubscriptionID) (internal.Subscription, error) {
+	op := "postgresql.subscription.Get"
+	defer metrics.ObserveDBQuery("subscription", "Get", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	notFound := internal.ErrSubscriptionNotFound.With(map[string]any{"subscription": id})
+
+	subscriptionID, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil {
+		return internal.Subscription{}, fmt.Errorf("%s: %w", op, notFound)
+	}
+
+	query := `SELECT ` + subscriptionColumns + `
+              FROM webhook_subscriptions
+              WHERE id = $1 AND ($2 = '' OR api_key = $2)`
+
+	subscription, err := scanSubscription(ss.pgPool.QueryRow(ctx, query, subscriptionID, apiKey))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.Subscription{}, fmt.Errorf("%s: %w", op, notFound)
+		}
+		return internal.Subscription{}, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return subscription, nil
+}
+
+func (ss *SubscriptionStorage) List(ctx context.Context, apiKey string) ([]internal.Subscription, error) {
+	op := "postgresql.subscription.List"
+	defer metrics.ObserveDBQuery("subscription", "List", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `SELECT ` + subscriptionColumns + `
+              FROM webhook_subscriptions
+              WHERE api_key = $1
+              ORDER BY id`
+
+	subscriptions, err := ss.query(ctx, query, apiKey)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return subscriptions, nil
+}
+
+func (ss *SubscriptionStorage) ListByPair(ctx context.Context, pair string) ([]internal.Subscription, error) {
+	op := "postgresql.subscription.ListByPair"
+	defer metrics.ObserveDBQuery("subscription", "ListByPair", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `SELECT ` + subscriptionColumns + `
+              FROM webhook_subscriptions
+              WHERE pairs @> ARRAY[$1::text]`
+
+	subscriptions, err := ss.query(ctx, query, pair)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return subscriptions, nil
+}
+
+func (ss *SubscriptionStorage) Delete(ctx context.Context, apiKey string, id internal.SubscriptionID) error {
+	op := "postgresql.subscription.Delete"
+	defer metrics.ObserveDBQuery("subscription", "Delete", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	notFound := internal.ErrSubscriptionNotFound.With(map[string]any{"subscription": id})
+
+	subscriptionID, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, notFound)
+	}
+
+	// Журнал доставок удаляется вместе с подпиской через ON DELETE CASCADE.
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1 AND api_key = $2`
+	tag, err := ss.pgPool.Exec(ctx, query, subscriptionID, apiKey)
+	if err != nil {
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, notFound)
+	}
+
+	return nil
+}
+
+func (ss *SubscriptionStorage) EnqueueDelivery(ctx context.Context, delivery internal.Delivery) (bool, error) {
+	op := "postgresql.subscription.EnqueueDelivery"
+	defer metrics.ObserveDBQuery("subscription", "EnqueueDelivery", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	subscriptionID, err := strconv.ParseInt(string(delivery.SubscriptionID), 10, 64)
+	if err != nil {
+		return false, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	query := `INSERT INTO webhook_deliveries (subscription_id, event_key, payload, status, next_attempt_at, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              ON CONFLICT (subscription_id, event_key) DO NOTHING`
+
+	tag, err := ss.pgPool.Exec(ctx, query, subscriptionID, delivery.EventKey, delivery.Payload,
+		string(delivery.Status), delivery.NextAttemptAt, delivery.CreatedAt)
+	if err != nil {
+		return false, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (ss *SubscriptionStorage) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]internal.Delivery, error) {
+	op := "postgresql.subscription.DueDeliveries"
+	defer metrics.ObserveDBQuery("subscription", "DueDeliveries", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `SELECT ` + deliveryColumns + `
+              FROM webhook_deliveries
+              WHERE status = $1 AND next_attempt_at <= $2
+              ORDER BY next_attempt_at
+              LIMIT $3`
+
+	deliveries, err := ss.queryDeliveries(ctx, query, string(internal.DeliveryPending), now, limit)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return deliveries, nil
+}
+
+func (ss *SubscriptionStorage) UpdateDelivery(ctx context.Context, delivery internal.Delivery) error {
+	op := "postgresql.subscription.UpdateDelivery"
+	defer metrics.ObserveDBQuery("subscription", "UpdateDelivery", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	deliveryID, err := strconv.ParseInt(string(delivery.ID), 10, 64)
+	if err != nil {
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	var deliveredAt *time.Time
+	if !delivery.DeliveredAt.IsZero() {
+		deliveredAt = &delivery.DeliveredAt
+	}
+
+	query := `UPDATE webhook_deliveries
+              SET status = $2, attempts = $3, response_status = $4, last_error = $5, next_attempt_at = $6, delivered_at = $7
+              WHERE id = $1`
+
+	_, err = ss.pgPool.Exec(ctx, query, deliveryID, string(delivery.Status), delivery.Attempts,
+		nullableInt(delivery.ResponseStatus), nullableString(delivery.LastError), delivery.NextAttemptAt, deliveredAt)
+	if err != nil {
+		return tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return nil
+}
+
+func (ss *SubscriptionStorage) ListDeliveries(ctx context.Context, id internal.SubscriptionID, limit int) ([]internal.Delivery, error) {
+	op := "postgresql.subscription.ListDeliveries"
+	defer metrics.ObserveDBQuery("subscription", "ListDeliveries", time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	subscriptionID, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	query := `SELECT ` + deliveryColumns + `
+              FROM webhook_deliveries
+              WHERE subscription_id = $1
+              ORDER BY created_at DESC, id DESC
+              LIMIT $2`
+
+	deliveries, err := ss.queryDeliveries(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return deliveries, nil
+}
+
+func (ss *SubscriptionStorage) query(ctx context.Context, query string, args ...any) ([]internal.Subscription, error) {
+	rows, err := ss.pgPool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []internal.Subscription{}
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, subscription)
+	}
+
+	return result, rows.Err()
+}
+
+func scanSubscription(row pgx.Row) (internal.Subscription, error) {
+	var id int64
+	var conditionsJSON []byte
+	var subscription internal.Subscription
+
+	err := row.Scan(&id, &subscription.APIKey, &subscription.URL, &subscription.Secret,
+		&subscription.Pairs, &conditionsJSON, &subscription.CreatedAt)
+	if err != nil {
+		return internal.Subscription{}, err
+	}
+
+	var conditions []subscriptionCondition
+	if err := json.Unmarshal(conditionsJSON, &conditions); err != nil {
+		return internal.Subscription{}, err
+	}
+
+	subscription.ID = internal.SubscriptionID(strconv.FormatInt(id, 10))
+	for _, condition := range conditions {
+		subscription.Conditions = append(subscription.Conditions, internal.SubscriptionCondition{
+			Type:      internal.SubscriptionConditionType(condition.Type),
+			Threshold: condition.Threshold,
+			Percent:   condition.Percent,
+		})
+	}
+
+	return subscription, nil
+}
+
+const deliveryColumns string = `id, subscription_id, event_key, payload, status, attempts,
+              COALESCE(response_status, 0), COALESCE(last_error, ''), next_attempt_at, created_at, delivered_at`
+
+func (ss *SubscriptionStorage) queryDeliveries(ctx context.Context, query string, args ...any) ([]internal.Delivery, error) {
+	rows, err := ss.pgPool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []internal.Delivery{}
+	for rows.Next() {
+		var id, subscriptionID int64
+		var status string
+		var deliveredAt *time.Time
+		var delivery internal.Delivery
+
+		err := rows.Scan(&id, &subscriptionID, &delivery.EventKey, &delivery.Payload, &status, &delivery.Attempts,
+			&delivery.ResponseStatus, &delivery.LastError, &delivery.NextAttemptAt, &delivery.CreatedAt, &deliveredAt)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.ID = internal.DeliveryID(strconv.FormatInt(id, 10))
+		delivery.SubscriptionID = internal.SubscriptionID(strconv.FormatInt(subscriptionID, 10))
+		delivery.Status = internal.DeliveryStatus(status)
+		if deliveredAt != nil {
+			delivery.DeliveredAt = *deliveredAt
+		}
+
+		result = append(result, delivery)
+	}
+
+	return result, rows.Err()
+}
+
+func nullableInt(value int) *int {
+	if value == 0 {
+		return nil
+	}
+
+	return &value
+}
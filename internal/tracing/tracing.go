@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   string = "none"
+	ExporterStdout string = "stdout"
+	ExporterOTLP   string = "otlp"
+)
+
+const (
+	tracerName  string = "github.com/sashaem1/ExchangeRate"
+	serviceName string = "exchange-rate"
+)
+
+const (
+	BaseCurrencyKey     = attribute.Key("currency.base")
+	TargetCurrencyKey   = attribute.Key("currency.target")
+	TargetCurrenciesKey = attribute.Key("currency.targets")
+	DateKey             = attribute.Key("rate.date")
+	EffectiveDateKey    = attribute.Key("rate.date.effective")
+	CacheOutcomeKey     = attribute.Key("cache.outcome")
+	ProviderKey         = attribute.Key("rate.provider")
+	CircuitStateKey     = attribute.Key("rate.provider.circuit")
+)
+
+const (
+	CacheHit     string = "hit"
+	CacheMiss    string = "miss"
+	CachePartial string = "partial"
+)
+
+// Setup настраивает глобальный TracerProvider. exporter - none, stdout или
+// otlp; адрес OTLP коллектора и имя сервиса берутся из стандартных переменных
+// OTEL_EXPORTER_OTLP_ENDPOINT и OTEL_SERVICE_NAME. Возвращаемую функцию нужно
+// вызвать при остановке, чтобы выгрузить накопленные спаны.
+func Setup(ctx context.Context, exporter string) (func(ctx context.Context) error, error) {
+	op := "tracing.tracing.Setup"
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+
+	switch strings.ToLower(exporter) {
+	case "", ExporterNone:
+		return func(ctx context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("%s: unknown trace exporter %q", op, exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err = resource.Merge(res, resource.Environment())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func StartKind(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// Error отмечает спан как ошибочный и возвращает err без изменений, чтобы
+// его можно было использовать прямо в return.
+func Error(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
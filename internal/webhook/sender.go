@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal"
+	"github.com/sashaem1/ExchangeRate/internal/logger"
+	"github.com/sashaem1/ExchangeRate/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	HeaderDelivery  string = "X-Webhook-Delivery"
+	HeaderTimestamp string = "X-Webhook-Timestamp"
+	HeaderSignature string = "X-Webhook-Signature"
+
+	signaturePrefix string = "sha256="
+	// Тело ответа получателя не нужно, читается только начало, чтобы
+	// соединение можно было переиспользовать.
+	maxResponseBody int64 = 4 << 10
+)
+
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender создаёт отправителя, который соединяется только с адресами,
+// разрешёнными internal.WebhookAddressAllowed.
+func NewSender(timeout time.Duration) *Sender {
+	return newSender(timeout, internal.WebhookAddressAllowed)
+}
+
+// newSender проверяет адрес каждого соединения уже после разрешения имени,
+// поэтому DNS rebinding не обходит проверку. Прокси из окружения не
+// используется, так как соединение с ним скрыло бы адрес получателя.
+// Перенаправления не выполняются: ответ 3xx считается неуспешной доставкой.
+func newSender(timeout time.Duration, allowed func(netip.Addr) bool) *Sender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !allowed(addrPort.Addr()) {
+				return fmt.Errorf("webhook address %s is not allowed", addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Sender{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Sign возвращает подпись тела запроса: HMAC-SHA256 от "<timestamp>.<body>"
+// на секрете подписки. Метка времени входит в подпись, чтобы получатель мог
+// отбрасывать повторно отправленные старые запросы.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись, полученную в заголовке X-Webhook-Signature.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Send отправляет событие на вебхук. Ответ с кодом вне 2xx считается
+// ошибкой, статус при этом всё равно возвращается для журнала доставок.
+func (s *Sender) Send(ctx context.Context, webhookURL, secret string, delivery internal.Delivery) (int, error) {
+	op := "webhook.sender.Send"
+	ctx, span := tracing.StartKind(ctx, "POST webhook", trace.SpanKindClient)
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDelivery, string(delivery.ID))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, delivery.Payload))
+	if requestID := logger.RequestID(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// В url.Error попадает адрес вебхука, он может содержать токен
+		// получателя
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, tracing.Error(span, fmt.Errorf("%s: %w", op, err))
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, tracing.Error(span, fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode))
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sashaem1/ExchangeRate/internal"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"pair":"USD/EUR","rate":0.92}`)
+	timestamp := int64(1700000000)
+
+	signature := Sign("secret", timestamp, body)
+	if !Verify("secret", timestamp, body, signature) {
+		t.Fatalf("Verify(%q) = false, want true", signature)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+	}{
+		{"other secret", "other", timestamp, body},
+		{"other timestamp", "secret", timestamp + 1, body},
+		{"tampered body", "secret", timestamp, []byte(`{"pair":"USD/EUR","rate":0.93}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Verify(tt.secret, tt.timestamp, tt.body, signature) {
+				t.Errorf("Verify() = true, want false")
+			}
+		})
+	}
+}
+
+func TestSendSignsRequest(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	delivery := internal.Delivery{ID: "delivery-1", Payload: []byte(`{"event":"update"}`)}
+	sender := newSender(time.Second, func(netip.Addr) bool { return true })
+
+	status, err := sender.Send(context.Background(), server.URL, "secret", delivery)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Send() status = %d, want %d", status, http.StatusNoContent)
+	}
+
+	got := <-requests
+	if string(got.body) != string(delivery.Payload) {
+		t.Errorf("body = %q, want %q", got.body, delivery.Payload)
+	}
+	if id := got.header.Get(HeaderDelivery); id != string(delivery.ID) {
+		t.Errorf("%s = %q, want %q", HeaderDelivery, id, delivery.ID)
+	}
+	timestamp, err := strconv.ParseInt(got.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("%s: %v", HeaderTimestamp, err)
+	}
+	if !Verify("secret", timestamp, got.body, got.header.Get(HeaderSignature)) {
+		t.Errorf("%s = %q does not verify", HeaderSignature, got.header.Get(HeaderSignature))
+	}
+}
+
+func TestSendUnexpectedStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "redirect is not followed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					t.Errorf("redirect to %s was followed", r.URL.Path)
+				}
+				http.Redirect(w, r, "/other", http.StatusFound)
+			},
+			status: http.StatusFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			sender := newSender(time.Second, func(netip.Addr) bool { return true })
+			status, err := sender.Send(context.Background(), server.URL, "secret", internal.Delivery{ID: "delivery-1"})
+			if err == nil {
+				t.Fatalf("Send() error = nil, want error")
+			}
+			if status != tt.status {
+				t.Errorf("Send() status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestSendRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request to loopback address was sent")
+	}))
+	defer server.Close()
+
+	status, err := NewSender(time.Second).Send(context.Background(), server.URL, "secret", internal.Delivery{ID: "delivery-1"})
+	if err == nil {
+		t.Fatalf("Send() error = nil, want error")
+	}
+	if status != 0 {
+		t.Errorf("Send() status = %d, want 0", status)
+	}
+}